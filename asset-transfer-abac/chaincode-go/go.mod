@@ -4,5 +4,9 @@ go 1.15
 
 require (
 	github.com/hyperledger/fabric-contract-api-go v1.1.1
-	golang.org/x/tools v0.1.0 // indirect
+	github.com/hyperledger/fabric-samples/shared/chaincode-go v0.0.0
+	golang.org/x/net v0.0.0-20201021035429-f5854403a974 // indirect
+	golang.org/x/sys v0.0.0-20210119212857-b64e53b001e4 // indirect
 )
+
+replace github.com/hyperledger/fabric-samples/shared/chaincode-go => ../../shared/chaincode-go
@@ -1,11 +1,11 @@
 package abac
 
 import (
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
 )
 
 // SmartContract provides functions for managing an Asset
@@ -19,21 +19,16 @@ type Asset struct {
 	Color          string `json:"color"`
 	Size           int    `json:"size"`
 	Owner          string `json:"owner"`
+	OwnerMSP       string `json:"ownerMSP"`
 	AppraisedValue int    `json:"appraisedValue"`
 }
 
 // CreateAsset issues a new asset to the world state with given details.
+// The access policy of CreateAsset, evaluated by enforceAccessPolicy before the function is
+// called, demonstrates the use of Attribute-Based Access Control (ABAC). Unless an admin
+// stores another policy, the caller needs the "abac.creator" attribute with a value of true.
 func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface, id string, color string, size int, appraisedValue int) error {
 
-	// Demonstrate the use of Attribute-Based Access Control (ABAC) by checking
-	// to see if the caller has the "abac.creator" attribute with a value of true;
-	// if not, return an error.
-	//
-	err := ctx.GetClientIdentity().AssertAttributeValue("abac.creator", "true")
-	if err != nil {
-		return fmt.Errorf("submitting client not authorized to create asset, does not have abac.creator role")
-	}
-
 	exists, err := s.AssetExists(ctx, id)
 	if err != nil {
 		return err
@@ -48,11 +43,17 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface,
 		return err
 	}
 
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSPID: %v", err)
+	}
+
 	asset := Asset{
 		ID:             id,
 		Color:          color,
 		Size:           size,
 		Owner:          clientID,
+		OwnerMSP:       clientMSPID,
 		AppraisedValue: appraisedValue,
 	}
 	assetJSON, err := json.Marshal(asset)
@@ -64,6 +65,7 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface,
 }
 
 // UpdateAsset updates an existing asset in the world state with provided parameters.
+// The asset can be updated by its owner, or by an identity the owner delegated UpdateAsset to.
 func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface, id string, newColor string, newSize int, newValue int) error {
 
 	asset, err := s.ReadAsset(ctx, id)
@@ -71,15 +73,11 @@ func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface,
 		return err
 	}
 
-	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	err = s.assertOwnerOrDelegate(ctx, asset, "UpdateAsset")
 	if err != nil {
 		return err
 	}
 
-	if clientID != asset.Owner {
-		return fmt.Errorf("submitting client not authorized to update asset, does not own asset")
-	}
-
 	asset.Color = newColor
 	asset.Size = newSize
 	asset.AppraisedValue = newValue
@@ -92,7 +90,7 @@ func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface,
 	return ctx.GetStub().PutState(id, assetJSON)
 }
 
-// DeleteAsset deletes a given asset from the world state.
+// DeleteAsset deletes a given asset from the world state, along with its delegations.
 func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface, id string) error {
 
 	asset, err := s.ReadAsset(ctx, id)
@@ -100,19 +98,21 @@ func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface,
 		return err
 	}
 
-	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	err = s.assertOwnerOrDelegate(ctx, asset, "DeleteAsset")
 	if err != nil {
 		return err
 	}
 
-	if clientID != asset.Owner {
-		return fmt.Errorf("submitting client not authorized to update asset, does not own asset")
+	err = deleteDelegations(ctx, id)
+	if err != nil {
+		return err
 	}
 
 	return ctx.GetStub().DelState(id)
 }
 
 // TransferAsset updates the owner field of asset with given id in world state.
+// The new owner is an identity of the MSP that owns the asset. Delegations of the asset are removed.
 func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface, id string, newOwner string) error {
 
 	asset, err := s.ReadAsset(ctx, id)
@@ -120,13 +120,15 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 		return err
 	}
 
-	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	err = s.assertOwnerOrDelegate(ctx, asset, "TransferAsset")
 	if err != nil {
 		return err
 	}
 
-	if clientID != asset.Owner {
-		return fmt.Errorf("submitting client not authorized to update asset, does not own asset")
+	// Delegations granted by the previous owner do not carry over to the new owner
+	err = deleteDelegations(ctx, id)
+	if err != nil {
+		return err
 	}
 
 	asset.Owner = newOwner
@@ -197,18 +199,8 @@ func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface,
 	return assetJSON != nil, nil
 }
 
-// GetSubmittingClientIdentity returns the name and issuer of the identity that
-// invokes the smart contract. This function base64 decodes the identity string
-// before returning the value to the client or smart contract.
+// GetSubmittingClientIdentity returns the MSP ID, name and issuer of the identity
+// that invokes the smart contract, in the <mspID>::x509::<subject>::<issuer> format.
 func (s *SmartContract) GetSubmittingClientIdentity(ctx contractapi.TransactionContextInterface) (string, error) {
-
-	b64ID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return "", fmt.Errorf("Failed to read clientID: %v", err)
-	}
-	decodeID, err := base64.StdEncoding.DecodeString(b64ID)
-	if err != nil {
-		return "", fmt.Errorf("failed to base64 decode clientID: %v", err)
-	}
-	return string(decodeID), nil
+	return identity.ID(ctx.GetClientIdentity())
 }
@@ -0,0 +1,230 @@
+package abac
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
+)
+
+const delegationObjectType = "delegation"
+
+// delegableFunctions are the functions an owner can delegate to another identity
+var delegableFunctions = map[string]bool{
+	"UpdateAsset":   true,
+	"TransferAsset": true,
+	"DeleteAsset":   true,
+}
+
+// Delegation allows the Delegate identity to call Functions on an asset on behalf of its owner until Expiry
+type Delegation struct {
+	AssetID   string    `json:"assetID"`
+	Grantor   string    `json:"grantor"`
+	Delegate  string    `json:"delegate"`
+	Functions []string  `json:"functions"`
+	Expiry    time.Time `json:"expiry"`
+}
+
+// GrantDelegation allows the owner of an asset to delegate functions on the asset to another identity.
+// The expiry is an RFC3339 timestamp that is compared with the timestamp of the transactions of the delegate.
+// Granting a delegation to the same identity again replaces the previous delegation.
+func (s *SmartContract) GrantDelegation(ctx contractapi.TransactionContextInterface, id string, delegate string, functions []string, expiry string) error {
+
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !identity.Same(clientID, asset.Owner) {
+		return fmt.Errorf("submitting client not authorized to grant delegations, does not own asset")
+	}
+
+	if len(functions) == 0 {
+		return fmt.Errorf("at least one function must be delegated")
+	}
+	for _, function := range functions {
+		if !delegableFunctions[function] {
+			return fmt.Errorf("function %s cannot be delegated", function)
+		}
+	}
+
+	expiryTime, err := time.Parse(time.RFC3339, expiry)
+	if err != nil {
+		return fmt.Errorf("expiry must be an RFC3339 timestamp: %v", err)
+	}
+
+	txTime, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !expiryTime.After(txTime) {
+		return fmt.Errorf("expiry %s is not after the transaction timestamp %s", expiry, txTime.Format(time.RFC3339))
+	}
+
+	delegation := Delegation{
+		AssetID:   id,
+		Grantor:   clientID,
+		Delegate:  delegate,
+		Functions: functions,
+		Expiry:    expiryTime,
+	}
+	delegationJSON, err := json.Marshal(delegation)
+	if err != nil {
+		return err
+	}
+
+	delegationKey, err := ctx.GetStub().CreateCompositeKey(delegationObjectType, []string{id, identity.Normalize(delegate)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().PutState(delegationKey, delegationJSON)
+}
+
+// RevokeDelegation allows the owner of an asset to revoke a delegation before it expires
+func (s *SmartContract) RevokeDelegation(ctx contractapi.TransactionContextInterface, id string, delegate string) error {
+
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !identity.Same(clientID, asset.Owner) {
+		return fmt.Errorf("submitting client not authorized to revoke delegations, does not own asset")
+	}
+
+	delegationKey, err := ctx.GetStub().CreateCompositeKey(delegationObjectType, []string{id, identity.Normalize(delegate)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	delegationJSON, err := ctx.GetStub().GetState(delegationKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if delegationJSON == nil {
+		return fmt.Errorf("asset %s is not delegated to %s", id, delegate)
+	}
+
+	return ctx.GetStub().DelState(delegationKey)
+}
+
+// ListDelegations returns the delegations of an asset, including expired delegations
+func (s *SmartContract) ListDelegations(ctx contractapi.TransactionContextInterface, id string) ([]*Delegation, error) {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(delegationObjectType, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var delegations []*Delegation
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var delegation Delegation
+		err = json.Unmarshal(queryResponse.Value, &delegation)
+		if err != nil {
+			return nil, err
+		}
+		delegations = append(delegations, &delegation)
+	}
+
+	return delegations, nil
+}
+
+// assertOwnerOrDelegate checks that the submitting client owns the asset, or holds
+// a delegation from the owner for the function that has not expired
+func (s *SmartContract) assertOwnerOrDelegate(ctx contractapi.TransactionContextInterface, asset *Asset, function string) error {
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	if identity.Same(clientID, asset.Owner) {
+		return nil
+	}
+
+	delegationKey, err := ctx.GetStub().CreateCompositeKey(delegationObjectType, []string{asset.ID, identity.Normalize(clientID)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	delegationJSON, err := ctx.GetStub().GetState(delegationKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if delegationJSON == nil {
+		return fmt.Errorf("submitting client not authorized to update asset, does not own asset")
+	}
+
+	var delegation Delegation
+	err = json.Unmarshal(delegationJSON, &delegation)
+	if err != nil {
+		return err
+	}
+
+	if !identity.Same(delegation.Grantor, asset.Owner) || !containsString(delegation.Functions, function) {
+		return fmt.Errorf("submitting client not authorized to update asset, does not own asset")
+	}
+
+	txTime, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !txTime.Before(delegation.Expiry) {
+		return fmt.Errorf("submitting client not authorized to update asset, delegation expired at %s", delegation.Expiry.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// deleteDelegations removes all delegations of an asset, when it changes owner or is deleted
+func deleteDelegations(ctx contractapi.TransactionContextInterface, id string) error {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(delegationObjectType, []string{id})
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+
+		err = ctx.GetStub().DelState(queryResponse.Key)
+		if err != nil {
+			return fmt.Errorf("failed to delete delegation: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// getTxTime returns the timestamp of the transaction, which is the same on every endorsing peer
+func getTxTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	return time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC(), nil
+}
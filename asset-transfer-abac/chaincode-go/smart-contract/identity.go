@@ -0,0 +1,11 @@
+package abac
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
+)
+
+// WhoAmI returns the identity that submits the transaction, as read from its certificate
+func (s *SmartContract) WhoAmI(ctx contractapi.TransactionContextInterface) (*identity.ClientIdentityInfo, error) {
+	return identity.Describe(ctx.GetClientIdentity())
+}
@@ -0,0 +1,241 @@
+package abac
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const policyObjectType = "policy"
+
+// AccessPolicy describes the conditions a client must meet to call a smart contract function.
+// Each MSP manages its own policies, which apply to the clients of that MSP. For example, the
+// following policy allows only editors and admins from the MSP that owns an asset to update it:
+//
+//	{"function":"UpdateAsset","attributes":[{"name":"abac.role","values":["editor","admin"]}],"sameMSPAsOwner":true}
+type AccessPolicy struct {
+	MSPID          string               `json:"mspID"`
+	Function       string               `json:"function"`
+	Attributes     []AttributeCondition `json:"attributes"`
+	SameMSPAsOwner bool                 `json:"sameMSPAsOwner"`
+}
+
+// AttributeCondition requires the client certificate attribute Name to have one of Values
+type AttributeCondition struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// defaultPolicies are evaluated for functions that do not have a policy stored on the ledger
+var defaultPolicies = map[string]*AccessPolicy{
+	"CreateAsset": {
+		Function:   "CreateAsset",
+		Attributes: []AttributeCondition{{Name: "abac.creator", Values: []string{"true"}}},
+	},
+}
+
+// policyFunctions are managed by the admin check in each function, rather than by access policies
+var policyFunctions = map[string]bool{
+	"SetAccessPolicy":    true,
+	"DeleteAccessPolicy": true,
+}
+
+// GetBeforeTransaction makes the contract api evaluate the access policy before every function
+func (s *SmartContract) GetBeforeTransaction() interface{} {
+	return s.enforceAccessPolicy
+}
+
+// enforceAccessPolicy evaluates the access policy of the invoked function against the
+// attributes in the client certificate. Functions that operate on an asset take the
+// asset ID as their first argument, which is used to evaluate sameMSPAsOwner.
+func (s *SmartContract) enforceAccessPolicy(ctx contractapi.TransactionContextInterface) error {
+	function, params := ctx.GetStub().GetFunctionAndParameters()
+	// The function name may be qualified with the contract name
+	function = function[strings.LastIndex(function, ":")+1:]
+	if policyFunctions[function] {
+		return nil
+	}
+
+	policy, err := s.GetAccessPolicy(ctx, function)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return nil
+	}
+
+	for _, condition := range policy.Attributes {
+		value, found, err := ctx.GetClientIdentity().GetAttributeValue(condition.Name)
+		if err != nil {
+			return fmt.Errorf("failed to read attribute %s: %v", condition.Name, err)
+		}
+		if !found || !containsString(condition.Values, value) {
+			return fmt.Errorf("submitting client not authorized to call %s, attribute %s must be one of %v", function, condition.Name, condition.Values)
+		}
+	}
+
+	if policy.SameMSPAsOwner {
+		if len(params) == 0 {
+			return fmt.Errorf("submitting client not authorized to call %s, no asset to check the owner MSP of", function)
+		}
+
+		asset, err := s.ReadAsset(ctx, params[0])
+		if err != nil {
+			return err
+		}
+
+		clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+		if err != nil {
+			return fmt.Errorf("failed to get client MSPID: %v", err)
+		}
+
+		if clientMSPID != asset.OwnerMSP {
+			return fmt.Errorf("submitting client not authorized to call %s, client MSP %s is not the owner MSP %s", function, clientMSPID, asset.OwnerMSP)
+		}
+	}
+
+	return nil
+}
+
+// SetAccessPolicy creates or replaces the access policy of a function for the clients of the admin's MSP.
+// Only clients registered as admins by their certificate authority can manage policies.
+func (s *SmartContract) SetAccessPolicy(ctx contractapi.TransactionContextInterface, policyJSON string) error {
+	mspID, err := assertAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	var policy AccessPolicy
+	err = json.Unmarshal([]byte(policyJSON), &policy)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal policy JSON: %v", err)
+	}
+	if policy.Function == "" {
+		return fmt.Errorf("function field must be a non-empty string")
+	}
+	if policyFunctions[policy.Function] {
+		return fmt.Errorf("the access policy of %s cannot be changed", policy.Function)
+	}
+	for _, condition := range policy.Attributes {
+		if condition.Name == "" || len(condition.Values) == 0 {
+			return fmt.Errorf("each attribute condition must have a name and at least one value")
+		}
+	}
+
+	if policy.MSPID != "" && policy.MSPID != mspID {
+		return fmt.Errorf("admins of %s cannot manage the access policies of %s", mspID, policy.MSPID)
+	}
+	policy.MSPID = mspID
+
+	policyKey, err := ctx.GetStub().CreateCompositeKey(policyObjectType, []string{mspID, policy.Function})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	policyBytes, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(policyKey, policyBytes)
+}
+
+// DeleteAccessPolicy removes the access policy of a function that the admin's MSP stored on the
+// ledger, after which the default policy of the function applies to the clients of the MSP.
+func (s *SmartContract) DeleteAccessPolicy(ctx contractapi.TransactionContextInterface, function string) error {
+	mspID, err := assertAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	policyKey, err := ctx.GetStub().CreateCompositeKey(policyObjectType, []string{mspID, function})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(policyKey)
+}
+
+// GetAccessPolicy returns the access policy that applies to a function for the clients of
+// the submitting client's MSP, or nil if the function can be called by any client.
+func (s *SmartContract) GetAccessPolicy(ctx contractapi.TransactionContextInterface, function string) (*AccessPolicy, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSPID: %v", err)
+	}
+
+	policyKey, err := ctx.GetStub().CreateCompositeKey(policyObjectType, []string{mspID, function})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	policyJSON, err := ctx.GetStub().GetState(policyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if policyJSON == nil {
+		return defaultPolicies[function], nil
+	}
+
+	var policy AccessPolicy
+	err = json.Unmarshal(policyJSON, &policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// GetAllAccessPolicies returns the access policies that all MSPs stored on the ledger
+func (s *SmartContract) GetAllAccessPolicies(ctx contractapi.TransactionContextInterface) ([]*AccessPolicy, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(policyObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var policies []*AccessPolicy
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var policy AccessPolicy
+		err = json.Unmarshal(queryResponse.Value, &policy)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, &policy)
+	}
+
+	return policies, nil
+}
+
+// assertAdmin checks that the client was registered as an admin and returns the MSP of the
+// client, whose policies the admin manages. The Fabric CA adds the hf.Type attribute with
+// the identity type to every certificate it issues.
+func assertAdmin(ctx contractapi.TransactionContextInterface) (string, error) {
+	err := ctx.GetClientIdentity().AssertAttributeValue("hf.Type", "admin")
+	if err != nil {
+		return "", fmt.Errorf("submitting client not authorized to manage access policies, is not an admin")
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSPID: %v", err)
+	}
+
+	return mspID, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
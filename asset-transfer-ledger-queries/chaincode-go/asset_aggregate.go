@@ -0,0 +1,161 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// aggregationPageSize is the number of assets read from each page of the iterators
+// used to compute aggregates
+const aggregationPageSize = 100
+
+// AggregateGroup structure used for returning the aggregates of a group of assets.
+// Sum, Min, Max and Avg are computed over the aggregated field, and are zero when no field is aggregated.
+type AggregateGroup struct {
+	Key   string  `json:"key"`
+	Count int     `json:"count"`
+	Sum   int     `json:"sum"`
+	Min   int     `json:"min"`
+	Max   int     `json:"max"`
+	Avg   float64 `json:"avg"`
+}
+
+// AggregateAssets counts the assets grouped by an indexed field, and computes the sum, minimum,
+// maximum and average of a numeric field of the assets of each group, e.g. the sum of the appraised
+// value per owner. groupBy must be the first field of a declared index, or empty to aggregate all assets
+// in a single group. field is "size" or "appraisedValue", or empty to only count the assets.
+// The assets are read one page at a time, and only the aggregates are returned.
+// Paginated queries are only valid for read only transactions.
+func (t *SimpleChaincode) AggregateAssets(ctx contractapi.TransactionContextInterface, groupBy string, field string) ([]*AggregateGroup, error) {
+	if field != "" && field != "size" && field != "appraisedValue" {
+		return nil, fmt.Errorf("field %s cannot be aggregated, must be size or appraisedValue", field)
+	}
+
+	var idx *indexDefinition
+	if groupBy != "" {
+		for i := range assetIndexes {
+			if assetIndexes[i].Fields[0] == groupBy {
+				idx = &assetIndexes[i]
+				break
+			}
+		}
+		if idx == nil {
+			return nil, fmt.Errorf("assets cannot be grouped by %s, the field is not indexed", groupBy)
+		}
+	}
+
+	aggregation := &assetAggregation{field: field, groupsByKey: make(map[string]*AggregateGroup)}
+
+	bookmark := ""
+	for {
+		fetched, nextBookmark, err := aggregatePage(ctx, idx, bookmark, aggregation)
+		if err != nil {
+			return nil, err
+		}
+		if fetched == 0 || nextBookmark == "" || nextBookmark == bookmark {
+			break
+		}
+		bookmark = nextBookmark
+	}
+
+	for _, group := range aggregation.groups {
+		if field != "" && group.Count > 0 {
+			group.Avg = float64(group.Sum) / float64(group.Count)
+		}
+	}
+
+	return aggregation.groups, nil
+}
+
+// assetAggregation holds the groups computed by AggregateAssets, in the order they were found
+type assetAggregation struct {
+	field       string
+	groups      []*AggregateGroup
+	groupsByKey map[string]*AggregateGroup
+}
+
+// aggregatePage adds the assets of one page of results to the aggregation. The page is read
+// from the index when the assets are grouped, and from the full key space otherwise.
+// Returns the number of fetched records and the bookmark of the next page.
+func aggregatePage(ctx contractapi.TransactionContextInterface, idx *indexDefinition, bookmark string, aggregation *assetAggregation) (int32, string, error) {
+	var resultsIterator shim.StateQueryIteratorInterface
+	var metadata *peer.QueryResponseMetadata
+	var err error
+	if idx != nil {
+		resultsIterator, metadata, err = ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(idx.Name, []string{}, aggregationPageSize, bookmark)
+	} else {
+		resultsIterator, metadata, err = ctx.GetStub().GetStateByRangeWithPagination("", "", aggregationPageSize, bookmark)
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return 0, "", err
+		}
+
+		key := ""
+		assetBytes := queryResult.Value
+		if idx != nil {
+			_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResult.Key)
+			if err != nil {
+				return 0, "", err
+			}
+			key = compositeKeyParts[0]
+			assetBytes, err = ctx.GetStub().GetState(compositeKeyParts[len(compositeKeyParts)-1])
+			if err != nil {
+				return 0, "", err
+			}
+		}
+
+		var asset Asset
+		err = json.Unmarshal(assetBytes, &asset)
+		if err != nil || asset.DocType != "asset" {
+			continue
+		}
+
+		group, ok := aggregation.groupsByKey[key]
+		if !ok {
+			group = &AggregateGroup{Key: key}
+			aggregation.groupsByKey[key] = group
+			aggregation.groups = append(aggregation.groups, group)
+		}
+		addToGroup(group, asset, aggregation.field)
+	}
+
+	return metadata.FetchedRecordsCount, metadata.Bookmark, nil
+}
+
+// addToGroup adds an asset to the aggregates of a group
+func addToGroup(group *AggregateGroup, asset Asset, field string) {
+	group.Count++
+
+	var value int
+	switch field {
+	case "size":
+		value = asset.Size
+	case "appraisedValue":
+		value = asset.AppraisedValue
+	default:
+		return
+	}
+
+	group.Sum += value
+	if group.Count == 1 || value < group.Min {
+		group.Min = value
+	}
+	if group.Count == 1 || value > group.Max {
+		group.Max = value
+	}
+}
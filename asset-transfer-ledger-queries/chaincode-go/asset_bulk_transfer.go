@@ -0,0 +1,146 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// AssetSelector selects the assets of a bulk transfer. Empty fields are not used to select
+// assets, and a MaxSize of 0 does not limit the size. Sizes are inclusive.
+type AssetSelector struct {
+	Owner   string `json:"owner,omitempty"`
+	Color   string `json:"color,omitempty"`
+	MinSize int    `json:"minSize,omitempty"`
+	MaxSize int    `json:"maxSize,omitempty"`
+}
+
+// TransferredAsset describes an asset of a bulk transfer
+type TransferredAsset struct {
+	ID            string `json:"ID"`
+	PreviousOwner string `json:"previousOwner"`
+}
+
+// BulkTransferResult structure used for returning the summary of a bulk transfer
+type BulkTransferResult struct {
+	NewOwner string              `json:"newOwner"`
+	DryRun   bool                `json:"dryRun"`
+	Count    int                 `json:"count"`
+	Assets   []*TransferredAsset `json:"assets"`
+}
+
+// TransferAssetsBySelector transfers the assets matched by the selector to a new owner.
+// The transfer fails without transferring any asset if more than maxCount assets match.
+// In dry run mode, the matching assets are returned without being transferred.
+// The assets are found with range queries against the owner~name or color~name index,
+// or against the full key space when neither owner nor color is selected. Committing
+// peers re-execute range queries, so it is safe to update assets based on the results.
+func (t *SimpleChaincode) TransferAssetsBySelector(ctx contractapi.TransactionContextInterface, selector AssetSelector, newOwner string, maxCount int, dryRun bool) (*BulkTransferResult, error) {
+	if maxCount <= 0 {
+		return nil, fmt.Errorf("maxCount must be a positive integer")
+	}
+	if selector.MaxSize != 0 && selector.MaxSize < selector.MinSize {
+		return nil, fmt.Errorf("maxSize %d is smaller than minSize %d", selector.MaxSize, selector.MinSize)
+	}
+
+	assets, err := selectAssets(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(assets) > maxCount {
+		return nil, fmt.Errorf("%d assets match the selector, more than the maximum of %d", len(assets), maxCount)
+	}
+
+	result := &BulkTransferResult{
+		NewOwner: newOwner,
+		DryRun:   dryRun,
+		Count:    len(assets),
+		Assets:   []*TransferredAsset{},
+	}
+	for _, asset := range assets {
+		result.Assets = append(result.Assets, &TransferredAsset{ID: asset.ID, PreviousOwner: asset.Owner})
+		if dryRun {
+			continue
+		}
+
+		asset.Owner = newOwner
+		err = putAsset(ctx, asset)
+		if err != nil {
+			return nil, fmt.Errorf("transfer failed for asset %s: %v", asset.ID, err)
+		}
+	}
+
+	return result, nil
+}
+
+// selectAssets returns the assets that match the selector
+func selectAssets(ctx contractapi.TransactionContextInterface, selector AssetSelector) ([]*Asset, error) {
+	var resultsIterator shim.StateQueryIteratorInterface
+	var err error
+	useIndex := true
+	if selector.Owner != "" {
+		resultsIterator, err = ctx.GetStub().GetStateByPartialCompositeKey("owner~name", []string{selector.Owner})
+	} else if selector.Color != "" {
+		resultsIterator, err = ctx.GetStub().GetStateByPartialCompositeKey(index, []string{selector.Color})
+	} else {
+		useIndex = false
+		resultsIterator, err = ctx.GetStub().GetStateByRange("", "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var assets []*Asset
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		assetBytes := queryResult.Value
+		if useIndex {
+			_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResult.Key)
+			if err != nil {
+				return nil, err
+			}
+			assetBytes, err = ctx.GetStub().GetState(compositeKeyParts[len(compositeKeyParts)-1])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var asset Asset
+		err = json.Unmarshal(assetBytes, &asset)
+		if err != nil || asset.DocType != "asset" {
+			continue
+		}
+		if selector.matches(&asset) {
+			assets = append(assets, &asset)
+		}
+	}
+
+	return assets, nil
+}
+
+func (selector AssetSelector) matches(asset *Asset) bool {
+	if selector.Owner != "" && asset.Owner != selector.Owner {
+		return false
+	}
+	if selector.Color != "" && asset.Color != selector.Color {
+		return false
+	}
+	if asset.Size < selector.MinSize {
+		return false
+	}
+	if selector.MaxSize != 0 && asset.Size > selector.MaxSize {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,291 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// indexDefinition declares a secondary index on the asset. Each entry of the index is a
+// composite key made of the values of the declared fields followed by the asset ID,
+// e.g. color~name~blue~asset1. Fields are named by their JSON tag.
+type indexDefinition struct {
+	Name   string
+	Fields []string
+}
+
+// assetIndexes lists the indexes that are maintained whenever an asset is written or deleted
+var assetIndexes = []indexDefinition{
+	{Name: index, Fields: []string{"color"}},
+	{Name: "owner~name", Fields: []string{"owner"}},
+}
+
+// findIndex returns the declared index with the given name
+func findIndex(indexName string) (*indexDefinition, error) {
+	for i := range assetIndexes {
+		if assetIndexes[i].Name == indexName {
+			return &assetIndexes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("index %s is not declared", indexName)
+}
+
+// indexKeys returns the composite keys of all declared indexes for an asset
+func indexKeys(ctx contractapi.TransactionContextInterface, asset *Asset) ([]string, error) {
+	assetBytes, err := json.Marshal(asset)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	err = json.Unmarshal(assetBytes, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, idx := range assetIndexes {
+		var attributes []string
+		for _, field := range idx.Fields {
+			attributes = append(attributes, fmt.Sprint(fields[field]))
+		}
+		attributes = append(attributes, asset.ID)
+
+		key, err := ctx.GetStub().CreateCompositeKey(idx.Name, attributes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key for index %s: %v", idx.Name, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// putAsset writes an asset to the ledger. Index entries of the previous version of the
+// asset that no longer apply are removed, and the entries of the new version are added.
+func putAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	previousBytes, err := ctx.GetStub().GetState(asset.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get asset %s: %v", asset.ID, err)
+	}
+
+	newKeys, err := indexKeys(ctx, asset)
+	if err != nil {
+		return err
+	}
+
+	if previousBytes != nil {
+		var previous Asset
+		err = json.Unmarshal(previousBytes, &previous)
+		if err != nil {
+			return err
+		}
+		previousKeys, err := indexKeys(ctx, &previous)
+		if err != nil {
+			return err
+		}
+		for _, key := range previousKeys {
+			if !containsKey(newKeys, key) {
+				err = ctx.GetStub().DelState(key)
+				if err != nil {
+					return fmt.Errorf("failed to delete index entry: %v", err)
+				}
+			}
+		}
+	}
+
+	assetBytes, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(asset.ID, assetBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put asset %s: %v", asset.ID, err)
+	}
+
+	//  Save index entries to world state. Only the key name is needed, no need to store a duplicate copy of the asset.
+	//  Note - passing a 'nil' value will effectively delete the key from state, therefore we pass null character as value
+	for _, key := range newKeys {
+		err = ctx.GetStub().PutState(key, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to put index entry: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// deleteAsset removes an asset and its index entries from the ledger
+func deleteAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	err := ctx.GetStub().DelState(asset.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete asset %s: %v", asset.ID, err)
+	}
+
+	keys, err := indexKeys(ctx, asset)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = ctx.GetStub().DelState(key)
+		if err != nil {
+			return fmt.Errorf("failed to delete index entry: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// QueryAssetsByIndex returns the assets whose leading index fields match the given values.
+// The values are matched in the order of the fields of the index, and may cover only the first fields.
+// Example: GetStateByPartialCompositeKey on a declared index
+func (t *SimpleChaincode) QueryAssetsByIndex(ctx contractapi.TransactionContextInterface, indexName string, values []string) ([]*Asset, error) {
+	idx, err := findIndex(indexName)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) > len(idx.Fields) {
+		return nil, fmt.Errorf("index %s has %d fields, got %d values", indexName, len(idx.Fields), len(values))
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(idx.Name, values)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var assets []*Asset
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		asset, err := t.ReadAsset(ctx, compositeKeyParts[len(compositeKeyParts)-1])
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+
+	return assets, nil
+}
+
+// RebuildIndexes removes all entries of the declared indexes and recreates them from the
+// assets in the world state. It can be used after an index is added to assetIndexes, or to
+// repair indexes of assets that were written without them. Only admins of an organization can rebuild the indexes.
+// Returns the number of assets that were indexed.
+func (t *SimpleChaincode) RebuildIndexes(ctx contractapi.TransactionContextInterface) (int, error) {
+	err := assertAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, idx := range assetIndexes {
+		err = deleteIndexEntries(ctx, idx.Name)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// A range query over the full key space does not return composite keys,
+	// so only the assets themselves are returned
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return 0, err
+	}
+	defer resultsIterator.Close()
+
+	count := 0
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResult.Value, &asset)
+		if err != nil || asset.DocType != "asset" {
+			continue
+		}
+
+		keys, err := indexKeys(ctx, &asset)
+		if err != nil {
+			return 0, err
+		}
+		for _, key := range keys {
+			err = ctx.GetStub().PutState(key, []byte{0x00})
+			if err != nil {
+				return 0, fmt.Errorf("failed to put index entry: %v", err)
+			}
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// deleteIndexEntries removes all entries of an index from the world state
+func deleteIndexEntries(ctx contractapi.TransactionContextInterface, indexName string) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(indexName, []string{})
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().DelState(responseRange.Key)
+		if err != nil {
+			return fmt.Errorf("failed to delete index entry: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// assertAdmin checks that the submitting client is an admin, either by the admin
+// organizational unit of its certificate or by the hf.Type attribute added by the Fabric CA
+func assertAdmin(ctx contractapi.TransactionContextInterface) error {
+	err := ctx.GetClientIdentity().AssertAttributeValue("hf.Type", "admin")
+	if err == nil {
+		return nil
+	}
+
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return fmt.Errorf("failed to get client certificate: %v", err)
+	}
+	if cert == nil {
+		return fmt.Errorf("submitting client is not an admin")
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if strings.EqualFold(ou, "admin") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("submitting client is not an admin")
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// isRichQueryNotSupported returns true when a rich query failed because the peer uses
+// LevelDB as state database, which does not support JSON queries
+func isRichQueryNotSupported(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "not supported for leveldb")
+}
+
+// getQueryResultFromIndexes evaluates the selector of a query string in chaincode.
+// It is used on peers that use LevelDB as state database, and supports a subset of the
+// CouchDB selector syntax: equality, $eq, $gt, $lt, $in and $and. When the selector
+// requires an equality on the first field of a declared index, only the assets of that
+// index entry are read, otherwise all the assets in the world state are scanned.
+// The use_index and fields parts of the query are ignored.
+func getQueryResultFromIndexes(ctx contractapi.TransactionContextInterface, queryString string) ([]*Asset, error) {
+	var query map[string]json.RawMessage
+	err := json.Unmarshal([]byte(queryString), &query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query string: %v", err)
+	}
+	for part := range query {
+		if part != "selector" && part != "use_index" && part != "fields" {
+			return nil, fmt.Errorf("query %s is not supported on LevelDB", part)
+		}
+	}
+
+	var selector map[string]interface{}
+	err = json.Unmarshal(query["selector"], &selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query selector: %v", err)
+	}
+	// evaluate the selector once without a document to report unsupported operators up front
+	_, err = matchesSelector(selector, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	var resultsIterator shim.StateQueryIteratorInterface
+	idx, value := selectIndex(selector)
+	if idx != nil {
+		resultsIterator, err = ctx.GetStub().GetStateByPartialCompositeKey(idx.Name, []string{value})
+	} else {
+		resultsIterator, err = ctx.GetStub().GetStateByRange("", "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var assets []*Asset
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		assetBytes := queryResult.Value
+		if idx != nil {
+			_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResult.Key)
+			if err != nil {
+				return nil, err
+			}
+			assetBytes, err = ctx.GetStub().GetState(compositeKeyParts[len(compositeKeyParts)-1])
+			if err != nil {
+				return nil, err
+			}
+			if assetBytes == nil {
+				continue
+			}
+		}
+
+		var document map[string]interface{}
+		err = json.Unmarshal(assetBytes, &document)
+		if err != nil {
+			continue
+		}
+		match, err := matchesSelector(selector, document)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+
+		var asset Asset
+		err = json.Unmarshal(assetBytes, &asset)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, &asset)
+	}
+
+	return assets, nil
+}
+
+// selectIndex returns a declared index with a single field for which the selector
+// requires a string equality, along with the value of the field
+func selectIndex(selector map[string]interface{}) (*indexDefinition, string) {
+	for i := range assetIndexes {
+		idx := &assetIndexes[i]
+		if len(idx.Fields) != 1 {
+			continue
+		}
+		if value, ok := equalityValue(selector, idx.Fields[0]); ok {
+			return idx, value
+		}
+	}
+	return nil, ""
+}
+
+// equalityValue returns the string that a field must be equal to, if the selector requires it
+func equalityValue(selector map[string]interface{}, field string) (string, bool) {
+	if condition, ok := selector[field]; ok {
+		if operators, ok := condition.(map[string]interface{}); ok {
+			condition = operators["$eq"]
+		}
+		if value, ok := condition.(string); ok {
+			return value, true
+		}
+	}
+
+	if conditions, ok := selector["$and"].([]interface{}); ok {
+		for _, condition := range conditions {
+			if subSelector, ok := condition.(map[string]interface{}); ok {
+				if value, ok := equalityValue(subSelector, field); ok {
+					return value, true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// matchesSelector returns true when the document matches all the conditions of the selector
+func matchesSelector(selector map[string]interface{}, document map[string]interface{}) (bool, error) {
+	match := true
+	for field, condition := range selector {
+		var fieldMatch bool
+		var err error
+		if field == "$and" {
+			fieldMatch, err = matchesAnd(condition, document)
+		} else if strings.HasPrefix(field, "$") {
+			return false, fmt.Errorf("selector operator %s is not supported on LevelDB", field)
+		} else {
+			fieldMatch, err = matchesCondition(condition, document[field])
+		}
+		if err != nil {
+			return false, err
+		}
+		// keep evaluating so that unsupported operators are always reported
+		match = match && fieldMatch
+	}
+	return match, nil
+}
+
+func matchesAnd(condition interface{}, document map[string]interface{}) (bool, error) {
+	conditions, ok := condition.([]interface{})
+	if !ok {
+		return false, fmt.Errorf("$and requires an array of selectors")
+	}
+
+	match := true
+	for _, c := range conditions {
+		subSelector, ok := c.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("$and requires an array of selectors")
+		}
+		subMatch, err := matchesSelector(subSelector, document)
+		if err != nil {
+			return false, err
+		}
+		match = match && subMatch
+	}
+	return match, nil
+}
+
+// matchesCondition evaluates the condition on a single field. A condition that is
+// not an object of operators is an implicit $eq.
+func matchesCondition(condition interface{}, value interface{}) (bool, error) {
+	operators, ok := condition.(map[string]interface{})
+	if !ok {
+		return reflect.DeepEqual(condition, value), nil
+	}
+
+	match := true
+	for operator, operand := range operators {
+		var operatorMatch bool
+		switch operator {
+		case "$eq":
+			operatorMatch = reflect.DeepEqual(operand, value)
+		case "$gt":
+			operatorMatch = compareValues(value, operand) > 0
+		case "$lt":
+			operatorMatch = compareValues(value, operand) < 0
+		case "$in":
+			operands, ok := operand.([]interface{})
+			if !ok {
+				return false, fmt.Errorf("$in requires an array of values")
+			}
+			for _, o := range operands {
+				if reflect.DeepEqual(o, value) {
+					operatorMatch = true
+					break
+				}
+			}
+		default:
+			return false, fmt.Errorf("selector operator %s is not supported on LevelDB", operator)
+		}
+		match = match && operatorMatch
+	}
+	return match, nil
+}
+
+// compareValues compares two numbers or two strings. Values of other or different
+// types are not ordered, and compare as equal so that neither $gt nor $lt matches.
+func compareValues(a interface{}, b interface{}) int {
+	switch aValue := a.(type) {
+	case float64:
+		if bValue, ok := b.(float64); ok {
+			if aValue < bValue {
+				return -1
+			} else if aValue > bValue {
+				return 1
+			}
+		}
+	case string:
+		if bValue, ok := b.(string); ok {
+			return strings.Compare(aValue, bValue)
+		}
+	}
+	return 0
+}
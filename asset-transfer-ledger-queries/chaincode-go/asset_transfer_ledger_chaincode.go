@@ -11,14 +11,22 @@ peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["CreateAsset","asse
 peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["CreateAsset","asset3","blue","6","tom","70"]}'
 peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["TransferAsset","asset2","jerry"]}'
 peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["TransferAssetByColor","blue","jerry"]}'
+peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["TransferAssetsBySelector","{\"owner\":\"jerry\",\"minSize\":5}","tom","10","true"]}'
 peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["DeleteAsset","asset1"]}'
 
 ==== Query assets ====
 peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["ReadAsset","asset1"]}'
 peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["GetAssetsByRange","asset1","asset3"]}'
 peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["GetAssetHistory","asset1"]}'
+peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["GetAssetHistoryWithPagination","asset1","10",""]}'
+peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["ReadAssetAsOf","asset1","2021-01-01T00:00:00Z"]}'
+peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["AggregateAssets","owner","appraisedValue"]}'
+peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["QueryAssetsByIndex","owner~name","[\"tom\"]"]}'
 
-Rich Query (Only supported if CouchDB is used as state database):
+==== Maintain indexes (admin only) ====
+peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["RebuildIndexes"]}'
+
+Rich Query (On LevelDB, a subset of the selector syntax is evaluated in chaincode: $eq, $gt, $lt, $in and $and):
 peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["QueryAssetsByOwner","tom"]}'
 peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["QueryAssets","{\"selector\":{\"owner\":\"tom\"}}"]}'
 
@@ -78,6 +86,7 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
 )
 
 const index = "color~name"
@@ -111,6 +120,14 @@ type PaginatedQueryResult struct {
 	Bookmark            string   `json:"bookmark"`
 }
 
+// PaginatedHistoryResult structure used for returning a page of the history of an asset.
+// The bookmark is the transaction ID of the last record of the page, and is empty on the last page.
+type PaginatedHistoryResult struct {
+	Records             []HistoryQueryResult `json:"records"`
+	FetchedRecordsCount int32                `json:"fetchedRecordsCount"`
+	Bookmark            string               `json:"bookmark"`
+}
+
 // CreateAsset initializes a new asset in the ledger
 func (t *SimpleChaincode) CreateAsset(ctx contractapi.TransactionContextInterface, assetID, color string, size int, owner string, appraisedValue int) error {
 	exists, err := t.AssetExists(ctx, assetID)
@@ -129,29 +146,13 @@ func (t *SimpleChaincode) CreateAsset(ctx contractapi.TransactionContextInterfac
 		Owner:          owner,
 		AppraisedValue: appraisedValue,
 	}
-	assetBytes, err := json.Marshal(asset)
-	if err != nil {
-		return err
-	}
-
-	err = ctx.GetStub().PutState(assetID, assetBytes)
-	if err != nil {
-		return err
-	}
-
-	//  Create an index to enable color-based range queries, e.g. return all blue assets.
+	//  putAsset also creates the entries of the declared indexes, e.g. the color~name index
+	//  that enables color-based range queries, e.g. return all blue assets.
 	//  An 'index' is a normal key-value entry in the ledger.
 	//  The key is a composite key, with the elements that you want to range query on listed first.
 	//  In our case, the composite key is based on indexName~color~name.
 	//  This will enable very efficient state range queries based on composite keys matching indexName~color~*
-	colorNameIndexKey, err := ctx.GetStub().CreateCompositeKey(index, []string{asset.Color, asset.ID})
-	if err != nil {
-		return err
-	}
-	//  Save index entry to world state. Only the key name is needed, no need to store a duplicate copy of the asset.
-	//  Note - passing a 'nil' value will effectively delete the key from state, therefore we pass null character as value
-	value := []byte{0x00}
-	return ctx.GetStub().PutState(colorNameIndexKey, value)
+	return putAsset(ctx, asset)
 }
 
 // ReadAsset retrieves an asset from the ledger
@@ -180,18 +181,8 @@ func (t *SimpleChaincode) DeleteAsset(ctx contractapi.TransactionContextInterfac
 		return err
 	}
 
-	err = ctx.GetStub().DelState(assetID)
-	if err != nil {
-		return fmt.Errorf("failed to delete asset %s: %v", assetID, err)
-	}
-
-	colorNameIndexKey, err := ctx.GetStub().CreateCompositeKey(index, []string{asset.Color, asset.ID})
-	if err != nil {
-		return err
-	}
-
-	// Delete index entry
-	return ctx.GetStub().DelState(colorNameIndexKey)
+	// Delete the asset along with its index entries
+	return deleteAsset(ctx, asset)
 }
 
 // TransferAsset transfers an asset by setting a new owner name on the asset
@@ -202,12 +193,7 @@ func (t *SimpleChaincode) TransferAsset(ctx contractapi.TransactionContextInterf
 	}
 
 	asset.Owner = newOwner
-	assetBytes, err := json.Marshal(asset)
-	if err != nil {
-		return err
-	}
-
-	return ctx.GetStub().PutState(assetID, assetBytes)
+	return putAsset(ctx, asset)
 }
 
 // constructQueryResponseFromIterator constructs a slice of assets from the resultsIterator
@@ -280,11 +266,7 @@ func (t *SimpleChaincode) TransferAssetByColor(ctx contractapi.TransactionContex
 				return err
 			}
 			asset.Owner = newOwner
-			assetBytes, err := json.Marshal(asset)
-			if err != nil {
-				return err
-			}
-			err = ctx.GetStub().PutState(returnedAssetID, assetBytes)
+			err = putAsset(ctx, asset)
 			if err != nil {
 				return fmt.Errorf("transfer failed for asset %s: %v", returnedAssetID, err)
 			}
@@ -297,7 +279,7 @@ func (t *SimpleChaincode) TransferAssetByColor(ctx contractapi.TransactionContex
 // QueryAssetsByOwner queries for assets based on the owners name.
 // This is an example of a parameterized query where the query logic is baked into the chaincode,
 // and accepting a single query parameter (owner).
-// Only available on state databases that support rich query (e.g. CouchDB)
+// On LevelDB the query is answered from the owner~name index.
 // Example: Parameterized rich query
 func (t *SimpleChaincode) QueryAssetsByOwner(ctx contractapi.TransactionContextInterface, owner string) ([]*Asset, error) {
 	queryString := fmt.Sprintf(`{"selector":{"docType":"asset","owner":"%s"}}`, owner)
@@ -308,7 +290,8 @@ func (t *SimpleChaincode) QueryAssetsByOwner(ctx contractapi.TransactionContextI
 // Query string matching state database syntax is passed in and executed as is.
 // Supports ad hoc queries that can be defined at runtime by the client.
 // If this is not desired, follow the QueryAssetsForOwner example for parameterized queries.
-// Only available on state databases that support rich query (e.g. CouchDB)
+// On state databases that do not support rich query (e.g. LevelDB), the selector is evaluated
+// in chaincode, supporting equality, $eq, $gt, $lt, $in and $and.
 // Example: Ad hoc rich query
 func (t *SimpleChaincode) QueryAssets(ctx contractapi.TransactionContextInterface, queryString string) ([]*Asset, error) {
 	return getQueryResultForQueryString(ctx, queryString)
@@ -316,9 +299,13 @@ func (t *SimpleChaincode) QueryAssets(ctx contractapi.TransactionContextInterfac
 
 // getQueryResultForQueryString executes the passed in query string.
 // The result set is built and returned as a byte array containing the JSON results.
+// If the state database does not support rich queries, the query is evaluated in chaincode.
 func getQueryResultForQueryString(ctx contractapi.TransactionContextInterface, queryString string) ([]*Asset, error) {
 	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
 	if err != nil {
+		if isRichQueryNotSupported(err) {
+			return getQueryResultFromIndexes(ctx, queryString)
+		}
 		return nil, err
 	}
 	defer resultsIterator.Close()
@@ -394,33 +381,141 @@ func (t *SimpleChaincode) GetAssetHistory(ctx contractapi.TransactionContextInte
 			return nil, err
 		}
 
-		var asset Asset
-		if len(response.Value) > 0 {
-			err = json.Unmarshal(response.Value, &asset)
+		record, err := newHistoryQueryResult(assetID, response)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *record)
+	}
+
+	return records, nil
+}
+
+// GetAssetHistoryWithPagination returns a page of the history of an asset. The records are
+// returned in the order of the history database, and the bookmark returned with a page is
+// passed to get the next page. Pass an empty bookmark to get the first page.
+func (t *SimpleChaincode) GetAssetHistoryWithPagination(ctx contractapi.TransactionContextInterface, assetID string, pageSize int, bookmark string) (*PaginatedHistoryResult, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("pageSize must be a positive integer")
+	}
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	// skip the records up to and including the bookmark
+	if bookmark != "" {
+		found := false
+		for !found && resultsIterator.HasNext() {
+			response, err := resultsIterator.Next()
 			if err != nil {
 				return nil, err
 			}
-		} else {
-			asset = Asset{
-				ID: assetID,
-			}
+			found = response.TxId == bookmark
+		}
+		if !found {
+			return nil, fmt.Errorf("bookmark %s is not a transaction in the history of asset %s", bookmark, assetID)
 		}
+	}
 
-		timestamp, err := ptypes.Timestamp(response.Timestamp)
+	records := []HistoryQueryResult{}
+	for len(records) < pageSize && resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
 		if err != nil {
 			return nil, err
 		}
 
-		record := HistoryQueryResult{
-			TxId:      response.TxId,
-			Timestamp: timestamp,
-			Record:    &asset,
-			IsDelete:  response.IsDelete,
+		record, err := newHistoryQueryResult(assetID, response)
+		if err != nil {
+			return nil, err
 		}
-		records = append(records, record)
+		records = append(records, *record)
 	}
 
-	return records, nil
+	nextBookmark := ""
+	if len(records) > 0 && resultsIterator.HasNext() {
+		nextBookmark = records[len(records)-1].TxId
+	}
+
+	return &PaginatedHistoryResult{
+		Records:             records,
+		FetchedRecordsCount: int32(len(records)),
+		Bookmark:            nextBookmark,
+	}, nil
+}
+
+// ReadAssetAsOf returns the asset as it was at the given RFC3339 timestamp, which is the
+// version written by the last transaction with a timestamp at or before that moment.
+func (t *SimpleChaincode) ReadAssetAsOf(ctx contractapi.TransactionContextInterface, assetID string, timestamp string) (*Asset, error) {
+	asOf, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("timestamp must be in RFC3339 format: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	// the history is not assumed to be ordered, so every record is compared
+	var version *HistoryQueryResult
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := newHistoryQueryResult(assetID, response)
+		if err != nil {
+			return nil, err
+		}
+		if record.Timestamp.After(asOf) {
+			continue
+		}
+		if version == nil || record.Timestamp.After(version.Timestamp) {
+			version = record
+		}
+	}
+
+	if version == nil {
+		return nil, fmt.Errorf("asset %s did not exist at %s", assetID, timestamp)
+	}
+	if version.IsDelete {
+		return nil, fmt.Errorf("asset %s was deleted at %s", assetID, version.Timestamp.Format(time.RFC3339))
+	}
+
+	return version.Record, nil
+}
+
+// newHistoryQueryResult builds a history record from a modification of an asset.
+// Deleted assets have no value, and are returned with only their ID.
+func newHistoryQueryResult(assetID string, response *queryresult.KeyModification) (*HistoryQueryResult, error) {
+	var asset Asset
+	if len(response.Value) > 0 {
+		err := json.Unmarshal(response.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		asset = Asset{
+			ID: assetID,
+		}
+	}
+
+	timestamp, err := ptypes.Timestamp(response.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HistoryQueryResult{
+		TxId:      response.TxId,
+		Timestamp: timestamp,
+		Record:    &asset,
+		IsDelete:  response.IsDelete,
+	}, nil
 }
 
 // AssetExists returns true when asset with given ID exists in the ledger.
@@ -0,0 +1,364 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// AssetLineage describes the assets an asset was split or merged from, and the assets it
+// was split or merged into
+type AssetLineage struct {
+	ID       string   `json:"assetID"`
+	Parents  []*Asset `json:"parents"`
+	Children []*Asset `json:"children"`
+}
+
+// SplitAsset splits an asset into child assets. The sizes of the children must add up to the
+// size of the parent, and the appraised value of the parent is divided between the children in
+// proportion to their size. The child assets are owned by the owner of the parent and keep a
+// link to the parent, which remains in the assetCollection as a record of the split but can no
+// longer be transferred.
+func (s *SmartContract) SplitAsset(ctx contractapi.TransactionContextInterface) error {
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+
+	// Split details are private, therefore they get passed in transient field
+	transientSplitJSON, ok := transientMap["asset_split"]
+	if !ok {
+		return fmt.Errorf("asset_split key not found in the transient map")
+	}
+
+	type childInput struct {
+		ID   string `json:"assetID"`
+		Size int    `json:"size"`
+	}
+
+	type assetSplitTransientInput struct {
+		ID       string       `json:"assetID"`
+		Children []childInput `json:"children"`
+	}
+
+	var splitInput assetSplitTransientInput
+	err = json.Unmarshal(transientSplitJSON, &splitInput)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	if len(splitInput.ID) == 0 {
+		return fmt.Errorf("assetID field must be a non-empty string")
+	}
+	if len(splitInput.Children) < 2 {
+		return fmt.Errorf("children field must contain at least two assets")
+	}
+
+	// Verify that the client is submitting request to peer in their organization
+	err = verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return fmt.Errorf("SplitAsset cannot be performed: Error %v", err)
+	}
+
+	parent, parentDetails, err := s.readOwnedAsset(ctx, splitInput.ID)
+	if err != nil {
+		return err
+	}
+
+	totalSize := 0
+	childIDs := []string{}
+	seen := make(map[string]bool)
+	for _, child := range splitInput.Children {
+		if len(child.ID) == 0 {
+			return fmt.Errorf("assetID field of each child must be a non-empty string")
+		}
+		if seen[child.ID] {
+			return fmt.Errorf("asset %v is listed more than once", child.ID)
+		}
+		seen[child.ID] = true
+		if child.Size <= 0 {
+			return fmt.Errorf("size field of each child must be a positive integer")
+		}
+		totalSize += child.Size
+		childIDs = append(childIDs, child.ID)
+	}
+	if totalSize != parent.Size {
+		return fmt.Errorf("sizes of the children add up to %v, but the size of %v is %v", totalSize, parent.ID, parent.Size)
+	}
+
+	// Divide the appraised value in proportion to size, the last child receives the remainder
+	// so that the values of the children add up to the value of the parent
+	remainingValue := parentDetails.AppraisedValue
+	for i, child := range splitInput.Children {
+		appraisedValue := parentDetails.AppraisedValue * child.Size / parent.Size
+		if i == len(splitInput.Children)-1 {
+			appraisedValue = remainingValue
+		}
+		if appraisedValue <= 0 {
+			return fmt.Errorf("appraised value of %v is too small to be split into %v", parent.ID, child.ID)
+		}
+		remainingValue -= appraisedValue
+
+		childAsset := &Asset{
+			Type:      parent.Type,
+			ID:        child.ID,
+			Color:     parent.Color,
+			Size:      child.Size,
+			Owner:     parent.Owner,
+			ParentIDs: []string{parent.ID},
+		}
+		err = s.putNewAsset(ctx, childAsset, appraisedValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.retireAssets(ctx, []*Asset{parent}, childIDs)
+}
+
+// MergeAssets merges assets of the same type and color into a new asset. The size and the
+// appraised value of the new asset are the sums of the merged assets. The merged assets
+// remain in the assetCollection as a record of the merge but can no longer be transferred.
+func (s *SmartContract) MergeAssets(ctx contractapi.TransactionContextInterface) error {
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+
+	// Merge details are private, therefore they get passed in transient field
+	transientMergeJSON, ok := transientMap["asset_merge"]
+	if !ok {
+		return fmt.Errorf("asset_merge key not found in the transient map")
+	}
+
+	type assetMergeTransientInput struct {
+		IDs   []string `json:"assetIDs"`
+		NewID string   `json:"newAssetID"`
+	}
+
+	var mergeInput assetMergeTransientInput
+	err = json.Unmarshal(transientMergeJSON, &mergeInput)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	if len(mergeInput.IDs) < 2 {
+		return fmt.Errorf("assetIDs field must contain at least two assets")
+	}
+	if len(mergeInput.NewID) == 0 {
+		return fmt.Errorf("newAssetID field must be a non-empty string")
+	}
+
+	// Verify that the client is submitting request to peer in their organization
+	err = verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return fmt.Errorf("MergeAssets cannot be performed: Error %v", err)
+	}
+
+	parents := []*Asset{}
+	seen := make(map[string]bool)
+	totalSize := 0
+	totalValue := 0
+	for _, assetID := range mergeInput.IDs {
+		if seen[assetID] {
+			return fmt.Errorf("asset %v is listed more than once", assetID)
+		}
+		seen[assetID] = true
+
+		parent, parentDetails, err := s.readOwnedAsset(ctx, assetID)
+		if err != nil {
+			return err
+		}
+		if len(parents) > 0 && (parent.Type != parents[0].Type || parent.Color != parents[0].Color) {
+			return fmt.Errorf("asset %v does not have the same objectType and color as %v", assetID, parents[0].ID)
+		}
+
+		parents = append(parents, parent)
+		totalSize += parent.Size
+		totalValue += parentDetails.AppraisedValue
+	}
+
+	mergedAsset := &Asset{
+		Type:      parents[0].Type,
+		ID:        mergeInput.NewID,
+		Color:     parents[0].Color,
+		Size:      totalSize,
+		Owner:     parents[0].Owner,
+		ParentIDs: mergeInput.IDs,
+	}
+	err = s.putNewAsset(ctx, mergedAsset, totalValue)
+	if err != nil {
+		return err
+	}
+
+	return s.retireAssets(ctx, parents, []string{mergeInput.NewID})
+}
+
+// ReadAssetLineage returns the assets that an asset was split or merged from and the assets
+// that it was split or merged into
+func (s *SmartContract) ReadAssetLineage(ctx contractapi.TransactionContextInterface, assetID string) (*AssetLineage, error) {
+
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if asset == nil {
+		return nil, fmt.Errorf("%v does not exist", assetID)
+	}
+
+	parents, err := s.readAssets(ctx, asset.ParentIDs)
+	if err != nil {
+		return nil, err
+	}
+	children, err := s.readAssets(ctx, asset.ChildIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AssetLineage{
+		ID:       assetID,
+		Parents:  parents,
+		Children: children,
+	}, nil
+}
+
+// readOwnedAsset is an internal helper function that reads an asset that can be split or
+// merged by the submitting client, together with its private details from the client's
+// org specific collection
+func (s *SmartContract) readOwnedAsset(ctx contractapi.TransactionContextInterface, assetID string) (*Asset, *AssetPrivateDetails, error) {
+
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading asset: %v", err)
+	}
+	if asset == nil {
+		return nil, nil, fmt.Errorf("%v does not exist", assetID)
+	}
+	if len(asset.ChildIDs) > 0 {
+		return nil, nil, fmt.Errorf("%v has already been split or merged", assetID)
+	}
+
+	clientID, err := submittingClientIdentity(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if clientID != asset.Owner {
+		return nil, nil, fmt.Errorf("submitting client identity does not own asset %v", assetID)
+	}
+
+	orgCollection, err := getCollectionName(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to infer private collection name for the org: %v", err)
+	}
+
+	assetDetails, err := s.ReadAssetPrivateDetails(ctx, orgCollection, assetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if assetDetails == nil {
+		return nil, nil, fmt.Errorf("private details of %v not found in collection %v", assetID, orgCollection)
+	}
+
+	return asset, assetDetails, nil
+}
+
+// putNewAsset is an internal helper function that puts an asset created by a split or a merge
+// in the assetCollection, and its appraised value in the owners org specific collection
+func (s *SmartContract) putNewAsset(ctx contractapi.TransactionContextInterface, asset *Asset, appraisedValue int) error {
+
+	existing, err := ctx.GetStub().GetPrivateData(assetCollection, asset.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get asset: %v", err)
+	} else if existing != nil {
+		return fmt.Errorf("this asset already exists: " + asset.ID)
+	}
+
+	assetJSONasBytes, err := json.Marshal(asset)
+	if err != nil {
+		return fmt.Errorf("failed to marshal asset into JSON: %v", err)
+	}
+
+	log.Printf("Put: collection %v, ID %v, parents %v", assetCollection, asset.ID, asset.ParentIDs)
+	err = ctx.GetStub().PutPrivateData(assetCollection, asset.ID, assetJSONasBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put asset into private data collecton: %v", err)
+	}
+
+	assetPrivateDetailsAsBytes, err := json.Marshal(AssetPrivateDetails{
+		ID:             asset.ID,
+		AppraisedValue: appraisedValue,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal into JSON: %v", err)
+	}
+
+	orgCollection, err := getCollectionName(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to infer private collection name for the org: %v", err)
+	}
+
+	log.Printf("Put: collection %v, ID %v", orgCollection, asset.ID)
+	err = ctx.GetStub().PutPrivateData(orgCollection, asset.ID, assetPrivateDetailsAsBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put asset private details: %v", err)
+	}
+
+	return nil
+}
+
+// retireAssets is an internal helper function that links split or merged assets to the
+// assets that replace them, and removes their private details from the owners collection
+func (s *SmartContract) retireAssets(ctx contractapi.TransactionContextInterface, assets []*Asset, childIDs []string) error {
+
+	orgCollection, err := getCollectionName(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to infer private collection name for the org: %v", err)
+	}
+
+	for _, asset := range assets {
+		asset.ChildIDs = childIDs
+		assetJSONasBytes, err := json.Marshal(asset)
+		if err != nil {
+			return fmt.Errorf("failed marshalling asset %v: %v", asset.ID, err)
+		}
+
+		log.Printf("Put: collection %v, ID %v, children %v", assetCollection, asset.ID, childIDs)
+		err = ctx.GetStub().PutPrivateData(assetCollection, asset.ID, assetJSONasBytes)
+		if err != nil {
+			return err
+		}
+
+		err = ctx.GetStub().DelPrivateData(orgCollection, asset.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readAssets is an internal helper function that reads a list of assets from the assetCollection
+func (s *SmartContract) readAssets(ctx contractapi.TransactionContextInterface, assetIDs []string) ([]*Asset, error) {
+	assets := []*Asset{}
+	for _, assetID := range assetIDs {
+		asset, err := s.ReadAsset(ctx, assetID)
+		if err != nil {
+			return nil, err
+		}
+		if asset == nil {
+			return nil, fmt.Errorf("%v does not exist", assetID)
+		}
+		assets = append(assets, asset)
+	}
+	return assets, nil
+}
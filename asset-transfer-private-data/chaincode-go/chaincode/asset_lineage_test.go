@@ -0,0 +1,205 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-private-data/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-private-data/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitAssetBadInput(t *testing.T) {
+	transactionContext, chaincodeStub := prepMocksAsOrg1()
+	assetTransferCC := chaincode.SmartContract{}
+
+	err := assetTransferCC.SplitAsset(transactionContext)
+	require.EqualError(t, err, "asset_split key not found in the transient map")
+
+	setTransientInput(t, chaincodeStub, "asset_split", map[string]interface{}{
+		"assetID":  "id1",
+		"children": []map[string]interface{}{{"assetID": "id1a", "size": 7}},
+	})
+	err = assetTransferCC.SplitAsset(transactionContext)
+	require.EqualError(t, err, "children field must contain at least two assets")
+
+	setPrivateDataInStub(t, chaincodeStub, map[string]map[string]interface{}{
+		assetCollectionName:  {"id1": &chaincode.Asset{ID: "id1", Type: "testfulasset", Color: "gray", Size: 7, Owner: myOrg1Clientid}},
+		myOrg1PrivCollection: {"id1": &chaincode.AssetPrivateDetails{ID: "id1", AppraisedValue: 500}},
+	})
+	setTransientInput(t, chaincodeStub, "asset_split", map[string]interface{}{
+		"assetID":  "id1",
+		"children": []map[string]interface{}{{"assetID": "id1a", "size": 3}, {"assetID": "id1b", "size": 3}},
+	})
+	err = assetTransferCC.SplitAsset(transactionContext)
+	require.EqualError(t, err, "sizes of the children add up to 6, but the size of id1 is 7")
+
+	transactionContext, chaincodeStub = prepMocksAsOrg2()
+	setPrivateDataInStub(t, chaincodeStub, map[string]map[string]interface{}{
+		assetCollectionName: {"id1": &chaincode.Asset{ID: "id1", Type: "testfulasset", Color: "gray", Size: 7, Owner: myOrg1Clientid}},
+	})
+	setTransientInput(t, chaincodeStub, "asset_split", map[string]interface{}{
+		"assetID":  "id1",
+		"children": []map[string]interface{}{{"assetID": "id1a", "size": 3}, {"assetID": "id1b", "size": 4}},
+	})
+	err = assetTransferCC.SplitAsset(transactionContext)
+	require.EqualError(t, err, "submitting client identity does not own asset id1")
+}
+
+func TestSplitAssetSuccessful(t *testing.T) {
+	transactionContext, chaincodeStub := prepMocksAsOrg1()
+	assetTransferCC := chaincode.SmartContract{}
+
+	parent := &chaincode.Asset{ID: "id1", Type: "testfulasset", Color: "gray", Size: 7, Owner: myOrg1Clientid}
+	setPrivateDataInStub(t, chaincodeStub, map[string]map[string]interface{}{
+		assetCollectionName:  {"id1": parent},
+		myOrg1PrivCollection: {"id1": &chaincode.AssetPrivateDetails{ID: "id1", AppraisedValue: 500}},
+	})
+	setTransientInput(t, chaincodeStub, "asset_split", map[string]interface{}{
+		"assetID":  "id1",
+		"children": []map[string]interface{}{{"assetID": "id1a", "size": 3}, {"assetID": "id1b", "size": 4}},
+	})
+	err := assetTransferCC.SplitAsset(transactionContext)
+	require.NoError(t, err)
+
+	expectedPuts := []struct {
+		collection string
+		id         string
+		value      interface{}
+	}{
+		{assetCollectionName, "id1a", &chaincode.Asset{ID: "id1a", Type: "testfulasset", Color: "gray", Size: 3, Owner: myOrg1Clientid, ParentIDs: []string{"id1"}}},
+		{myOrg1PrivCollection, "id1a", &chaincode.AssetPrivateDetails{ID: "id1a", AppraisedValue: 214}},
+		{assetCollectionName, "id1b", &chaincode.Asset{ID: "id1b", Type: "testfulasset", Color: "gray", Size: 4, Owner: myOrg1Clientid, ParentIDs: []string{"id1"}}},
+		{myOrg1PrivCollection, "id1b", &chaincode.AssetPrivateDetails{ID: "id1b", AppraisedValue: 286}},
+		{assetCollectionName, "id1", &chaincode.Asset{ID: "id1", Type: "testfulasset", Color: "gray", Size: 7, Owner: myOrg1Clientid, ChildIDs: []string{"id1a", "id1b"}}},
+	}
+	require.Equal(t, len(expectedPuts), chaincodeStub.PutPrivateDataCallCount())
+	for i, expected := range expectedPuts {
+		expectedBytes, err := json.Marshal(expected.value)
+		require.NoError(t, err)
+		calledCollection, calledID, calledBytes := chaincodeStub.PutPrivateDataArgsForCall(i)
+		require.Equal(t, expected.collection, calledCollection)
+		require.Equal(t, expected.id, calledID)
+		require.Equal(t, expectedBytes, calledBytes)
+	}
+
+	calledCollection, calledID := chaincodeStub.DelPrivateDataArgsForCall(0)
+	require.Equal(t, myOrg1PrivCollection, calledCollection)
+	require.Equal(t, "id1", calledID)
+}
+
+func TestMergeAssets(t *testing.T) {
+	transactionContext, chaincodeStub := prepMocksAsOrg1()
+	assetTransferCC := chaincode.SmartContract{}
+
+	setPrivateDataInStub(t, chaincodeStub, map[string]map[string]interface{}{
+		assetCollectionName: {
+			"id1": &chaincode.Asset{ID: "id1", Type: "testfulasset", Color: "gray", Size: 3, Owner: myOrg1Clientid},
+			"id2": &chaincode.Asset{ID: "id2", Type: "testfulasset", Color: "gray", Size: 4, Owner: myOrg1Clientid},
+			"id3": &chaincode.Asset{ID: "id3", Type: "testfulasset", Color: "blue", Size: 4, Owner: myOrg1Clientid},
+		},
+		myOrg1PrivCollection: {
+			"id1": &chaincode.AssetPrivateDetails{ID: "id1", AppraisedValue: 100},
+			"id2": &chaincode.AssetPrivateDetails{ID: "id2", AppraisedValue: 200},
+			"id3": &chaincode.AssetPrivateDetails{ID: "id3", AppraisedValue: 300},
+		},
+	})
+
+	setTransientInput(t, chaincodeStub, "asset_merge", map[string]interface{}{"assetIDs": []string{"id1", "id3"}, "newAssetID": "id4"})
+	err := assetTransferCC.MergeAssets(transactionContext)
+	require.EqualError(t, err, "asset id3 does not have the same objectType and color as id1")
+
+	setTransientInput(t, chaincodeStub, "asset_merge", map[string]interface{}{"assetIDs": []string{"id1", "id2"}, "newAssetID": "id3"})
+	err = assetTransferCC.MergeAssets(transactionContext)
+	require.EqualError(t, err, "this asset already exists: id3")
+
+	setTransientInput(t, chaincodeStub, "asset_merge", map[string]interface{}{"assetIDs": []string{"id1", "id2"}, "newAssetID": "id4"})
+	err = assetTransferCC.MergeAssets(transactionContext)
+	require.NoError(t, err)
+
+	expectedMerged, err := json.Marshal(&chaincode.Asset{ID: "id4", Type: "testfulasset", Color: "gray", Size: 7, Owner: myOrg1Clientid, ParentIDs: []string{"id1", "id2"}})
+	require.NoError(t, err)
+	calledCollection, calledID, calledBytes := chaincodeStub.PutPrivateDataArgsForCall(0)
+	require.Equal(t, assetCollectionName, calledCollection)
+	require.Equal(t, "id4", calledID)
+	require.Equal(t, expectedMerged, calledBytes)
+
+	expectedDetails, err := json.Marshal(&chaincode.AssetPrivateDetails{ID: "id4", AppraisedValue: 300})
+	require.NoError(t, err)
+	calledCollection, calledID, calledBytes = chaincodeStub.PutPrivateDataArgsForCall(1)
+	require.Equal(t, myOrg1PrivCollection, calledCollection)
+	require.Equal(t, "id4", calledID)
+	require.Equal(t, expectedDetails, calledBytes)
+
+	require.Equal(t, 2, chaincodeStub.DelPrivateDataCallCount())
+}
+
+func TestReadAssetLineage(t *testing.T) {
+	transactionContext, chaincodeStub := prepMocksAsOrg1()
+	assetTransferCC := chaincode.SmartContract{}
+
+	parent := &chaincode.Asset{ID: "id1", Type: "testfulasset", Size: 7, Owner: myOrg1Clientid, ChildIDs: []string{"id1a"}}
+	child := &chaincode.Asset{ID: "id1a", Type: "testfulasset", Size: 7, Owner: myOrg1Clientid, ParentIDs: []string{"id1"}}
+	setPrivateDataInStub(t, chaincodeStub, map[string]map[string]interface{}{
+		assetCollectionName: {"id1": parent, "id1a": child},
+	})
+
+	lineage, err := assetTransferCC.ReadAssetLineage(transactionContext, "id1a")
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.Asset{parent}, lineage.Parents)
+	require.Equal(t, []*chaincode.Asset{}, lineage.Children)
+
+	_, err = assetTransferCC.ReadAssetLineage(transactionContext, "id2")
+	require.EqualError(t, err, "id2 does not exist")
+}
+
+func TestDeleteAssetWithLineage(t *testing.T) {
+	transactionContext, chaincodeStub := prepMocksAsOrg1()
+	assetTransferCC := chaincode.SmartContract{}
+
+	parent := &chaincode.Asset{ID: "id1", Type: "testfulasset", Size: 7, Owner: myOrg1Clientid, ChildIDs: []string{"id1a"}}
+	child := &chaincode.Asset{ID: "id1a", Type: "testfulasset", Size: 7, Owner: myOrg1Clientid, ParentIDs: []string{"id1"}}
+	setPrivateDataInStub(t, chaincodeStub, map[string]map[string]interface{}{
+		assetCollectionName:  {"id1": parent, "id1a": child},
+		myOrg1PrivCollection: {"id1a": &chaincode.AssetPrivateDetails{ID: "id1a", AppraisedValue: 500}},
+	})
+
+	setTransientInput(t, chaincodeStub, "asset_delete", map[string]interface{}{"assetID": "id1a"})
+	err := assetTransferCC.DeleteAsset(transactionContext)
+	require.EqualError(t, err, "asset id1a is part of the lineage of other assets and cannot be deleted")
+
+	setTransientInput(t, chaincodeStub, "asset_delete", map[string]interface{}{"assetID": "id1"})
+	err = assetTransferCC.DeleteAsset(transactionContext)
+	require.EqualError(t, err, "asset id1 is part of the lineage of other assets and cannot be deleted")
+	require.Equal(t, 0, chaincodeStub.DelPrivateDataCallCount())
+
+	lineage, err := assetTransferCC.ReadAssetLineage(transactionContext, "id1")
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.Asset{child}, lineage.Children)
+}
+
+func setTransientInput(t *testing.T, chaincodeStub *mocks.ChaincodeStub, key string, input interface{}) {
+	inputBytes, err := json.Marshal(input)
+	require.NoError(t, err)
+	chaincodeStub.GetTransientReturns(map[string][]byte{key: inputBytes}, nil)
+}
+
+// setPrivateDataInStub makes GetPrivateData return the given values, keyed by collection and key
+func setPrivateDataInStub(t *testing.T, chaincodeStub *mocks.ChaincodeStub, data map[string]map[string]interface{}) {
+	stored := make(map[string][]byte)
+	for collection, values := range data {
+		for key, value := range values {
+			valueBytes, err := json.Marshal(value)
+			require.NoError(t, err)
+			stored[collection+"/"+key] = valueBytes
+		}
+	}
+	chaincodeStub.GetPrivateDataStub = func(collection string, key string) ([]byte, error) {
+		return stored[collection+"/"+key], nil
+	}
+}
@@ -7,6 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package chaincode
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -14,6 +17,30 @@ import (
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// PaginatedQueryResult structure used for returning paginated query results and metadata
+type PaginatedQueryResult struct {
+	Records             []*Asset `json:"records"`
+	FetchedRecordsCount int32    `json:"fetchedRecordsCount"`
+	Bookmark            string   `json:"bookmark"`
+}
+
+// PaginatedPrivateDetailsResult structure used for returning paginated private details query results and metadata
+type PaginatedPrivateDetailsResult struct {
+	Records             []*AssetPrivateDetails `json:"records"`
+	FetchedRecordsCount int32                  `json:"fetchedRecordsCount"`
+	Bookmark            string                 `json:"bookmark"`
+}
+
+// PrivateDataVerification describes the result of comparing a claimed private data value
+// with the hash of the value that is recorded on the ledger
+type PrivateDataVerification struct {
+	ID          string `json:"assetID"`
+	Collection  string `json:"collection"`
+	ClaimedHash string `json:"claimedHash"`
+	LedgerHash  string `json:"ledgerHash"`
+	Verified    bool   `json:"verified"`
+}
+
 // ReadAsset reads the information from collection
 func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, assetID string) (*Asset, error) {
 
@@ -84,6 +111,54 @@ func (s *SmartContract) ReadTransferAgreement(ctx contractapi.TransactionContext
 	return agreement, nil
 }
 
+// VerifyAssetPrivateDetails allows a member of any organization, including one that is not a
+// member of the collection, to verify private data that was shared with it off-chain.
+// The claimed AssetPrivateDetails JSON is passed in the transient field "asset_private_details"
+// and its SHA-256 hash is compared with the hash of the value stored in the collection.
+// The claimed JSON must be byte for byte identical to the stored value for the hashes to match.
+// The result is returned in the endorsed proposal response, which is signed by the peer.
+func (s *SmartContract) VerifyAssetPrivateDetails(ctx contractapi.TransactionContextInterface, collection string, assetID string) (*PrivateDataVerification, error) {
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("error getting transient: %v", err)
+	}
+
+	// The claimed value is private, therefore it gets passed in transient field
+	claimedJSON, ok := transientMap["asset_private_details"]
+	if !ok {
+		return nil, fmt.Errorf("asset_private_details key not found in the transient map")
+	}
+
+	var claimedDetails AssetPrivateDetails
+	err = json.Unmarshal(claimedJSON, &claimedDetails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+	if claimedDetails.ID != assetID {
+		return nil, fmt.Errorf("assetID %v in the transient map does not match %v", claimedDetails.ID, assetID)
+	}
+
+	log.Printf("VerifyAssetPrivateDetails: collection %v, ID %v", collection, assetID)
+	ledgerHash, err := ctx.GetStub().GetPrivateDataHash(collection, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hash of private details from collection %v: %v", collection, err)
+	}
+	if ledgerHash == nil {
+		return nil, fmt.Errorf("hash of private details for %v does not exist in collection %v", assetID, collection)
+	}
+
+	claimedHash := sha256.Sum256(claimedJSON)
+
+	return &PrivateDataVerification{
+		ID:          assetID,
+		Collection:  collection,
+		ClaimedHash: hex.EncodeToString(claimedHash[:]),
+		LedgerHash:  hex.EncodeToString(ledgerHash),
+		Verified:    bytes.Equal(claimedHash[:], ledgerHash),
+	}, nil
+}
+
 // GetAssetByRange performs a range query based on the start and end keys provided. Range
 // queries can be used to read data from private data collections, but can not be used in
 // a transaction that also writes to private data.
@@ -188,3 +263,148 @@ func (s *SmartContract) getQueryResultForQueryString(ctx contractapi.Transaction
 	}
 	return results, nil
 }
+
+// ===== Example: Rich queries with pagination ===============================================
+// Private data collections do not support the paginated query APIs of the shim, so the
+// paging is done in chaincode. The bookmark returned with each page is the key of the last
+// record in that page; passing it back resumes the query with the record that follows it.
+// Paginated queries are only valid for read only transactions.
+// ============================================================================================
+
+// QueryAssetByOwnerWithPagination queries for assets based on assetType, owner, returning
+// at most pageSize assets starting after the bookmark.
+// Only available on state databases that support rich query (e.g. CouchDB)
+func (s *SmartContract) QueryAssetByOwnerWithPagination(ctx contractapi.TransactionContextInterface, assetType string, owner string, pageSize int, bookmark string) (*PaginatedQueryResult, error) {
+
+	queryString := fmt.Sprintf("{\"selector\":{\"objectType\":\"%v\",\"owner\":\"%v\"}}", assetType, owner)
+
+	return s.getQueryResultForQueryStringWithPagination(ctx, queryString, int32(pageSize), bookmark)
+}
+
+// QueryAssetsWithPagination uses a query string, page size and a bookmark to perform a query
+// for assets in the assetCollection.
+// Only available on state databases that support rich query (e.g. CouchDB)
+func (s *SmartContract) QueryAssetsWithPagination(ctx contractapi.TransactionContextInterface, queryString string, pageSize int, bookmark string) (*PaginatedQueryResult, error) {
+
+	return s.getQueryResultForQueryStringWithPagination(ctx, queryString, int32(pageSize), bookmark)
+}
+
+// QueryAssetPrivateDetailsByAppraisedValue queries the private collection of the caller's
+// organization for asset private details with an appraised value between minValue and maxValue
+// (inclusive), returning at most pageSize results starting after the bookmark.
+// Only available on state databases that support rich query (e.g. CouchDB)
+func (s *SmartContract) QueryAssetPrivateDetailsByAppraisedValue(ctx contractapi.TransactionContextInterface, minValue int, maxValue int, pageSize int, bookmark string) (*PaginatedPrivateDetailsResult, error) {
+
+	if minValue > maxValue {
+		return nil, fmt.Errorf("minValue %v is greater than maxValue %v", minValue, maxValue)
+	}
+
+	// Verify that the client is querying a peer in their organization, as only that
+	// peer holds the organization's private collection
+	err := verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("QueryAssetPrivateDetailsByAppraisedValue cannot be performed: Error %v", err)
+	}
+
+	orgCollection, err := getCollectionName(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to infer private collection name for the org: %v", err)
+	}
+
+	queryString := fmt.Sprintf("{\"selector\":{\"appraisedValue\":{\"$gte\":%d,\"$lte\":%d}},\"use_index\":[\"_design/indexAppraisedValueDoc\",\"indexAppraisedValue\"]}", minValue, maxValue)
+
+	values, newBookmark, err := getPrivateDataQueryPage(ctx, orgCollection, queryString, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*AssetPrivateDetails{}
+	for _, value := range values {
+		var assetDetails *AssetPrivateDetails
+		err = json.Unmarshal(value, &assetDetails)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		}
+		results = append(results, assetDetails)
+	}
+
+	return &PaginatedPrivateDetailsResult{
+		Records:             results,
+		FetchedRecordsCount: int32(len(results)),
+		Bookmark:            newBookmark,
+	}, nil
+}
+
+// getQueryResultForQueryStringWithPagination executes the passed in query string against the
+// assetCollection with pagination info.
+func (s *SmartContract) getQueryResultForQueryStringWithPagination(ctx contractapi.TransactionContextInterface, queryString string, pageSize int32, bookmark string) (*PaginatedQueryResult, error) {
+
+	values, newBookmark, err := getPrivateDataQueryPage(ctx, assetCollection, queryString, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*Asset{}
+	for _, value := range values {
+		var asset *Asset
+		err = json.Unmarshal(value, &asset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		}
+		results = append(results, asset)
+	}
+
+	return &PaginatedQueryResult{
+		Records:             results,
+		FetchedRecordsCount: int32(len(results)),
+		Bookmark:            newBookmark,
+	}, nil
+}
+
+// getPrivateDataQueryPage executes the query string against the collection and returns the
+// values of at most pageSize records that follow the record keyed by bookmark, together with
+// the bookmark of the next page. The returned bookmark is empty once the last page is reached.
+func getPrivateDataQueryPage(ctx contractapi.TransactionContextInterface, collection string, queryString string, pageSize int32, bookmark string) ([][]byte, string, error) {
+	if pageSize <= 0 {
+		return nil, "", fmt.Errorf("pageSize must be a positive integer")
+	}
+
+	resultsIterator, err := ctx.GetStub().GetPrivateDataQueryResult(collection, queryString)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resultsIterator.Close()
+
+	// Skip the records up to and including the bookmark
+	if bookmark != "" {
+		found := false
+		for !found && resultsIterator.HasNext() {
+			response, err := resultsIterator.Next()
+			if err != nil {
+				return nil, "", err
+			}
+			found = response.Key == bookmark
+		}
+		if !found {
+			return [][]byte{}, "", nil
+		}
+	}
+
+	values := [][]byte{}
+	lastKey := ""
+	for int32(len(values)) < pageSize && resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, "", err
+		}
+		values = append(values, response.Value)
+		lastKey = response.Key
+	}
+
+	// Only hand out a bookmark if there are records left to read
+	if !resultsIterator.HasNext() {
+		lastKey = ""
+	}
+
+	return values, lastKey, nil
+}
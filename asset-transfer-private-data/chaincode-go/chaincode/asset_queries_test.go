@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package chaincode_test
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -183,3 +185,116 @@ func TestGetAssetByRange(t *testing.T) {
 	require.Equal(t, []*chaincode.Asset{asset}, assets)
 
 }
+
+func TestQueryAssetsWithPagination(t *testing.T) {
+	transactionContext, chaincodeStub := prepMocksAsOrg1()
+	assetTransferCC := &chaincode.SmartContract{}
+
+	_, err := assetTransferCC.QueryAssetsWithPagination(transactionContext, "querystr", 0, "")
+	require.EqualError(t, err, "pageSize must be a positive integer")
+
+	asset1 := &chaincode.Asset{Type: "valuableasset", ID: "asset1", Owner: "user1"}
+	asset2 := &chaincode.Asset{Type: "valuableasset", ID: "asset2", Owner: "user1"}
+	asset3 := &chaincode.Asset{Type: "valuableasset", ID: "asset3", Owner: "user1"}
+	kvs := []*queryresult.KV{}
+	for _, asset := range []*chaincode.Asset{asset1, asset2, asset3} {
+		assetBytes, err := json.Marshal(asset)
+		require.NoError(t, err)
+		kvs = append(kvs, &queryresult.KV{Key: asset.ID, Value: assetBytes})
+	}
+
+	// First page
+	chaincodeStub.GetPrivateDataQueryResultReturns(newStateQueryIterator(kvs), nil)
+	page, err := assetTransferCC.QueryAssetsWithPagination(transactionContext, "querystr", 2, "")
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.Asset{asset1, asset2}, page.Records)
+	require.Equal(t, int32(2), page.FetchedRecordsCount)
+	require.Equal(t, "asset2", page.Bookmark)
+
+	// Last page resumes after the bookmark and does not return a new bookmark
+	chaincodeStub.GetPrivateDataQueryResultReturns(newStateQueryIterator(kvs), nil)
+	page, err = assetTransferCC.QueryAssetsWithPagination(transactionContext, "querystr", 2, page.Bookmark)
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.Asset{asset3}, page.Records)
+	require.Equal(t, int32(1), page.FetchedRecordsCount)
+	require.Equal(t, "", page.Bookmark)
+
+	// Unknown bookmark
+	chaincodeStub.GetPrivateDataQueryResultReturns(newStateQueryIterator(kvs), nil)
+	page, err = assetTransferCC.QueryAssetsWithPagination(transactionContext, "querystr", 2, "unknown")
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.Asset{}, page.Records)
+
+	iterator := &mocks.StateQueryIterator{}
+	iterator.HasNextReturns(true)
+	iterator.NextReturns(nil, fmt.Errorf("failed retrieving next item"))
+	chaincodeStub.GetPrivateDataQueryResultReturns(iterator, nil)
+	page, err = assetTransferCC.QueryAssetByOwnerWithPagination(transactionContext, "valuableasset", "user1", 2, "")
+	require.EqualError(t, err, "failed retrieving next item")
+	require.Nil(t, page)
+}
+
+func TestQueryAssetPrivateDetailsByAppraisedValue(t *testing.T) {
+	transactionContext, chaincodeStub := prepMocksAsOrg1()
+	assetTransferCC := &chaincode.SmartContract{}
+
+	_, err := assetTransferCC.QueryAssetPrivateDetailsByAppraisedValue(transactionContext, 10, 5, 10, "")
+	require.EqualError(t, err, "minValue 10 is greater than maxValue 5")
+
+	details := &chaincode.AssetPrivateDetails{ID: "asset1", AppraisedValue: 7}
+	detailsBytes, err := json.Marshal(details)
+	require.NoError(t, err)
+	chaincodeStub.GetPrivateDataQueryResultReturns(newStateQueryIterator([]*queryresult.KV{{Key: "asset1", Value: detailsBytes}}), nil)
+
+	page, err := assetTransferCC.QueryAssetPrivateDetailsByAppraisedValue(transactionContext, 5, 10, 10, "")
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.AssetPrivateDetails{details}, page.Records)
+	require.Equal(t, "", page.Bookmark)
+
+	calledCollection, calledQuery := chaincodeStub.GetPrivateDataQueryResultArgsForCall(0)
+	require.Equal(t, myOrg1PrivCollection, calledCollection)
+	require.Contains(t, calledQuery, `"appraisedValue":{"$gte":5,"$lte":10}`)
+}
+
+func newStateQueryIterator(kvs []*queryresult.KV) *mocks.StateQueryIterator {
+	iterator := &mocks.StateQueryIterator{}
+	for i, kv := range kvs {
+		iterator.HasNextReturnsOnCall(i, true)
+		iterator.NextReturnsOnCall(i, kv, nil)
+	}
+	iterator.HasNextReturnsOnCall(len(kvs), false)
+	return iterator
+}
+
+func TestVerifyAssetPrivateDetails(t *testing.T) {
+	transactionContext, chaincodeStub := prepMocksAsOrg2()
+	assetTransferCC := &chaincode.SmartContract{}
+
+	_, err := assetTransferCC.VerifyAssetPrivateDetails(transactionContext, myOrg1PrivCollection, "id1")
+	require.EqualError(t, err, "asset_private_details key not found in the transient map")
+
+	claimedBytes, err := json.Marshal(&chaincode.AssetPrivateDetails{ID: "id1", AppraisedValue: 500})
+	require.NoError(t, err)
+	chaincodeStub.GetTransientReturns(map[string][]byte{"asset_private_details": claimedBytes}, nil)
+
+	_, err = assetTransferCC.VerifyAssetPrivateDetails(transactionContext, myOrg1PrivCollection, "id2")
+	require.EqualError(t, err, "assetID id1 in the transient map does not match id2")
+
+	_, err = assetTransferCC.VerifyAssetPrivateDetails(transactionContext, myOrg1PrivCollection, "id1")
+	require.EqualError(t, err, "hash of private details for id1 does not exist in collection Org1TestmspPrivateCollection")
+
+	claimedHash := sha256.Sum256(claimedBytes)
+	chaincodeStub.GetPrivateDataHashReturns(claimedHash[:], nil)
+	result, err := assetTransferCC.VerifyAssetPrivateDetails(transactionContext, myOrg1PrivCollection, "id1")
+	require.NoError(t, err)
+	require.True(t, result.Verified)
+	require.Equal(t, hex.EncodeToString(claimedHash[:]), result.LedgerHash)
+	calledCollection, calledID := chaincodeStub.GetPrivateDataHashArgsForCall(1)
+	require.Equal(t, myOrg1PrivCollection, calledCollection)
+	require.Equal(t, "id1", calledID)
+
+	chaincodeStub.GetPrivateDataHashReturns([]byte("otherhash"), nil)
+	result, err = assetTransferCC.VerifyAssetPrivateDetails(transactionContext, myOrg1PrivCollection, "id1")
+	require.NoError(t, err)
+	require.False(t, result.Verified)
+}
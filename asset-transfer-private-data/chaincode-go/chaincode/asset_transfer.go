@@ -32,6 +32,9 @@ type Asset struct {
 	Color string `json:"color"`
 	Size  int    `json:"size"`
 	Owner string `json:"owner"`
+	// ParentIDs and ChildIDs link assets that were created by SplitAsset or MergeAssets
+	ParentIDs []string `json:"parentIDs,omitempty"`
+	ChildIDs  []string `json:"childIDs,omitempty"`
 }
 
 // AssetPrivateDetails describes details that are private to owners
@@ -212,6 +215,9 @@ func (s *SmartContract) AgreeToTransfer(ctx contractapi.TransactionContextInterf
 	if asset == nil {
 		return fmt.Errorf("%v does not exist", valueJSON.ID)
 	}
+	if len(asset.ChildIDs) > 0 {
+		return fmt.Errorf("%v has been split or merged and can no longer be transferred", valueJSON.ID)
+	}
 	// Verify that the client is submitting request to peer in their organization
 	err = verifyClientOrgMatchesPeerOrg(ctx)
 	if err != nil {
@@ -288,6 +294,9 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 	if asset == nil {
 		return fmt.Errorf("%v does not exist", assetTransferInput.ID)
 	}
+	if len(asset.ChildIDs) > 0 {
+		return fmt.Errorf("%v has been split or merged and can no longer be transferred", assetTransferInput.ID)
+	}
 	// Verify that the client is submitting request to peer in their organization
 	err = verifyClientOrgMatchesPeerOrg(ctx)
 	if err != nil {
@@ -445,6 +454,17 @@ func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface)
 		return fmt.Errorf("asset not found: %v", assetDeleteInput.ID)
 	}
 
+	// assets that were split or merged are linked to other assets, and are kept
+	// so that the lineage of the linked assets can still be read
+	var assetToDelete Asset
+	err = json.Unmarshal(valAsbytes, &assetToDelete)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+	if len(assetToDelete.ParentIDs) > 0 || len(assetToDelete.ChildIDs) > 0 {
+		return fmt.Errorf("asset %v is part of the lineage of other assets and cannot be deleted", assetDeleteInput.ID)
+	}
+
 	ownerCollection, err := getCollectionName(ctx) // Get owners collection
 	if err != nil {
 		return fmt.Errorf("failed to infer private collection name for the org: %v", err)
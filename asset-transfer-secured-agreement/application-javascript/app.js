@@ -60,6 +60,7 @@
 //
 
 const { Gateway, Wallets } = require('fabric-network');
+const crypto = require('crypto');
 const FabricCAServices = require('fabric-ca-client');
 const path = require('path');
 const { buildCAClient, registerAndEnrollUser, enrollAdmin } = require('../../test-application/javascript/CAUtil.js');
@@ -209,6 +210,8 @@ async function main() {
 		const randomNumber = Math.floor(Math.random() * 100) + 1;
 		// use a random key so that we can run multiple times
 		const assetKey = `asset-${randomNumber}`;
+		// the salt of the private properties must be at least 16 random bytes
+		const assetSalt = crypto.randomBytes(32).toString('hex');
 
 		/** ******* Fabric client init: Using Org1 identity to Org1 Peer ******* */
 		const gatewayOrg1 = await initGatewayForOrg1();
@@ -234,7 +237,7 @@ async function main() {
 					asset_id: assetKey,
 					color: 'blue',
 					size: 35,
-					salt: Buffer.from(randomNumber.toString()).toString('hex')
+					salt: assetSalt
 				};
 				const asset_properties_string = JSON.stringify(asset_properties);
 				console.log(`${GREEN}--> Submit Transaction: CreateAsset, ${assetKey} as Org1 - endorsed by Org1${RESET}`);
@@ -329,7 +332,7 @@ async function main() {
 					asset_id: assetKey,
 					color: 'blue',
 					size: 35,
-					salt: Buffer.from(randomNumber.toString()).toString('hex')
+					salt: assetSalt
 				};
 				const asset_properties_string = JSON.stringify(asset_properties);
 				console.log(`${GREEN}--> Evalute: VerifyAssetProperties, ${assetKey} as Org2 - endorsed by Org2${RESET}`);
@@ -400,7 +403,7 @@ async function main() {
 					asset_id: assetKey,
 					color: 'blue',
 					size: 35,
-					salt: Buffer.from(randomNumber.toString()).toString('hex')
+					salt: assetSalt
 				};
 				const asset_properties_string = JSON.stringify(asset_properties);
 				const asset_price = {
@@ -465,7 +468,7 @@ async function main() {
 					asset_id: assetKey,
 					color: 'blue',
 					size: 35,
-					salt: Buffer.from(randomNumber.toString()).toString('hex')
+					salt: assetSalt
 				};
 				const asset_properties_string = JSON.stringify(asset_properties);
 				const asset_price = {
@@ -497,7 +500,7 @@ async function main() {
 					asset_id: assetKey,
 					color: 'blue',
 					size: 35,
-					salt: Buffer.from(randomNumber.toString()).toString('hex')
+					salt: assetSalt
 				};
 				const asset_properties_string = JSON.stringify(asset_properties);
 				const asset_price = {
@@ -7,6 +7,7 @@ package main
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -23,6 +24,7 @@ const (
 	typeAssetBid         = "B"
 	typeAssetSaleReceipt = "SR"
 	typeAssetBuyReceipt  = "BR"
+	typeAssetBuyer       = "BI"
 )
 
 type SmartContract struct {
@@ -34,15 +36,25 @@ type Asset struct {
 	ObjectType        string `json:"objectType"` // ObjectType is used to distinguish different object types in the same chaincode namespace
 	ID                string `json:"assetID"`
 	OwnerOrg          string `json:"ownerOrg"`
+	Owner             string `json:"owner,omitempty"` // Owner is the hash of the client identity within OwnerOrg that owns the asset
 	PublicDescription string `json:"publicDescription"`
+	PropertiesRoot    string `json:"propertiesRoot,omitempty"` // PropertiesRoot is the Merkle root hash of the private properties
 }
 
-type receipt struct {
-	price     int
-	timestamp time.Time
+// Receipt records the price and date of a sale in the buyer's and seller's implicit private data collections
+type Receipt struct {
+	AssetID   string    `json:"assetID"`
+	TxID      string    `json:"txID"`
+	Price     int       `json:"price"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
-// CreateAsset creates an asset and sets it as owned by the client's org
+// buyerIdentity records which client of the buying org agreed to buy an asset
+type buyerIdentity struct {
+	BuyerIDHash string `json:"buyerIDHash"`
+}
+
+// CreateAsset creates an asset and sets it as owned by the client's org and identity
 func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface, assetID, publicDescription string) error {
 	transientMap, err := ctx.GetStub().GetTransient()
 	if err != nil {
@@ -62,11 +74,29 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("failed to get verified OrgID: %v", err)
 	}
 
+	clientIDHash, err := getClientIDHash(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Record the root hash of the private properties, so that selected properties
+	// can later be disclosed and verified without revealing the others. Properties
+	// without a salt are stored as before, but cannot be disclosed selectively.
+	var propertiesRoot string
+	if hasPropertiesSalt(immutablePropertiesJSON) {
+		propertiesRoot, err = computePropertiesRoot(immutablePropertiesJSON)
+		if err != nil {
+			return err
+		}
+	}
+
 	asset := Asset{
 		ObjectType:        "asset",
 		ID:                assetID,
 		OwnerOrg:          clientOrgID,
+		Owner:             clientIDHash,
 		PublicDescription: publicDescription,
+		PropertiesRoot:    propertiesRoot,
 	}
 	assetBytes, err := json.Marshal(asset)
 	if err != nil {
@@ -111,6 +141,10 @@ func (s *SmartContract) ChangePublicDescription(ctx contractapi.TransactionConte
 	if clientOrgID != asset.OwnerOrg {
 		return fmt.Errorf("a client from %s cannot update the description of a asset owned by %s", clientOrgID, asset.OwnerOrg)
 	}
+	err = verifyClientIsOwner(ctx, asset)
+	if err != nil {
+		return err
+	}
 
 	asset.PublicDescription = newDescription
 	updatedAssetJSON, err := json.Marshal(asset)
@@ -121,7 +155,8 @@ func (s *SmartContract) ChangePublicDescription(ctx contractapi.TransactionConte
 	return ctx.GetStub().PutState(assetID, updatedAssetJSON)
 }
 
-// AgreeToSell adds seller's asking price to seller's implicit private data collection
+// AgreeToSell adds seller's asking price to seller's implicit private data collection.
+// Calling AgreeToSell again posts a counter offer that replaces the asking price.
 func (s *SmartContract) AgreeToSell(ctx contractapi.TransactionContextInterface, assetID string) error {
 	asset, err := s.ReadAsset(ctx, assetID)
 	if err != nil {
@@ -137,13 +172,59 @@ func (s *SmartContract) AgreeToSell(ctx contractapi.TransactionContextInterface,
 	if clientOrgID != asset.OwnerOrg {
 		return fmt.Errorf("a client from %s cannot sell an asset owned by %s", clientOrgID, asset.OwnerOrg)
 	}
+	err = verifyClientIsOwner(ctx, asset)
+	if err != nil {
+		return err
+	}
 
 	return agreeToPrice(ctx, assetID, typeAssetForSale)
 }
 
-// AgreeToBuy adds buyer's bid price to buyer's implicit private data collection
+// AgreeToBuy adds buyer's bid price to buyer's implicit private data collection.
+// Calling AgreeToBuy again posts a counter offer that replaces the bid price.
+// The client identity that agrees to buy becomes the owner of the asset when it is transferred.
+// Once a client of the buyer org agreed to buy, only that client can post counter offers.
 func (s *SmartContract) AgreeToBuy(ctx contractapi.TransactionContextInterface, assetID string) error {
-	return agreeToPrice(ctx, assetID, typeAssetBid)
+	clientOrgID, err := getClientOrgID(ctx, true)
+	if err != nil {
+		return fmt.Errorf("failed to get verified OrgID: %v", err)
+	}
+
+	clientIDHash, err := getClientIDHash(ctx)
+	if err != nil {
+		return err
+	}
+
+	buyerKey, err := ctx.GetStub().CreateCompositeKey(typeAssetBuyer, []string{assetID, clientOrgID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	buyer, err := readBuyerIdentity(ctx, buyerKey)
+	if err != nil {
+		return err
+	}
+	if buyer != nil && buyer.BuyerIDHash != clientIDHash {
+		return fmt.Errorf("another client of org %s agreed to buy asset %s", clientOrgID, assetID)
+	}
+
+	err = agreeToPrice(ctx, assetID, typeAssetBid)
+	if err != nil {
+		return err
+	}
+
+	buyerJSON, err := json.Marshal(buyerIdentity{BuyerIDHash: clientIDHash})
+	if err != nil {
+		return fmt.Errorf("failed to marshal buyer identity: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(buyerKey, buyerJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put buyer identity: %v", err)
+	}
+
+	// Only the buyer org can endorse changes to the identity of its buyer
+	return setAssetStateBasedEndorsement(ctx, buyerKey, clientOrgID)
 }
 
 // agreeToPrice adds a bid or ask price to caller's implicit private data collection
@@ -181,6 +262,12 @@ func agreeToPrice(ctx contractapi.TransactionContextInterface, assetID string, p
 		return fmt.Errorf("failed to put asset bid: %v", err)
 	}
 
+	// Keep the price in the negotiation history, so that each round of offers can be reviewed
+	err = recordOffer(ctx, assetID, clientOrgID, priceType, price)
+	if err != nil {
+		return fmt.Errorf("failed to record offer: %v", err)
+	}
+
 	return nil
 }
 
@@ -229,6 +316,8 @@ func (s *SmartContract) VerifyAssetProperties(ctx contractapi.TransactionContext
 }
 
 // TransferAsset checks transfer conditions and then transfers asset state to buyer.
+// If the agreed price sets delivery_versus_payment, the price is also moved from the
+// buyer's balance to the seller's balance, so the asset and payment settle atomically.
 // TransferAsset can only be called by current owner
 func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface, assetID string, buyerOrgID string) error {
 	clientOrgID, err := getClientOrgID(ctx, false)
@@ -272,6 +361,14 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 		return fmt.Errorf("failed asset transfer: %v", err)
 	}
 
+	// Both parties agreed to the settlement mode as part of the hashed price agreement
+	if agreement.DeliveryVersusPayment {
+		err = settlePayment(ctx, buyerOrgID, clientOrgID, agreement.Price)
+		if err != nil {
+			return fmt.Errorf("failed payment settlement: %v", err)
+		}
+	}
+
 	return nil
 
 }
@@ -289,6 +386,10 @@ func verifyTransferConditions(ctx contractapi.TransactionContextInterface,
 	if clientOrgID != asset.OwnerOrg {
 		return fmt.Errorf("a client from %s cannot transfer a asset owned by %s", clientOrgID, asset.OwnerOrg)
 	}
+	err := verifyClientIsOwner(ctx, asset)
+	if err != nil {
+		return err
+	}
 
 	// CHECK2: Verify that the hash of the passed immutable properties matches the on-chain hash
 
@@ -370,7 +471,26 @@ func verifyTransferConditions(ctx contractapi.TransactionContextInterface,
 
 // transferAssetState performs the public and private state updates for the transferred asset
 func transferAssetState(ctx contractapi.TransactionContextInterface, asset *Asset, immutablePropertiesJSON []byte, clientOrgID string, buyerOrgID string, price int) error {
+	// The client of the buyer org that agreed to buy becomes the owner
+	buyerKey, err := ctx.GetStub().CreateCompositeKey(typeAssetBuyer, []string{asset.ID, buyerOrgID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key for buyer: %v", err)
+	}
+	buyer, err := readBuyerIdentity(ctx, buyerKey)
+	if err != nil {
+		return err
+	}
+	if buyer == nil {
+		return fmt.Errorf("buyer identity for %s does not exist", asset.ID)
+	}
+
+	err = ctx.GetStub().DelState(buyerKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete buyer identity: %v", err)
+	}
+
 	asset.OwnerOrg = buyerOrgID
+	asset.Owner = buyer.BuyerIDHash
 	updatedAsset, err := json.Marshal(asset)
 	if err != nil {
 		return err
@@ -422,6 +542,24 @@ func transferAssetState(ctx contractapi.TransactionContextInterface, asset *Asse
 		return fmt.Errorf("failed to delete asset price from implicit private data collection for buyer: %v", err)
 	}
 
+	// Delete the superseded offers of both seller and buyer
+	err = deleteOffers(ctx, asset.ID, clientOrgID, typeAssetForSale)
+	if err != nil {
+		return fmt.Errorf("failed to delete offers for seller: %v", err)
+	}
+
+	err = deleteOffers(ctx, asset.ID, buyerOrgID, typeAssetBid)
+	if err != nil {
+		return fmt.Errorf("failed to delete offers for buyer: %v", err)
+	}
+
+	// Delete the offer counters of the buyers that did not get the asset. Their offers
+	// remain in their own implicit collections until they call DeleteAssetOffers.
+	err = deleteOfferSequences(ctx, asset.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete offer counters: %v", err)
+	}
+
 	// Keep record for a 'receipt' in both buyers and sellers private data collection to record the sale price and date.
 	// Persist the agreed to price in a collection sub-namespace based on receipt key prefix.
 	receiptBuyKey, err := ctx.GetStub().CreateCompositeKey(typeAssetBuyReceipt, []string{asset.ID, ctx.GetStub().GetTxID()})
@@ -438,9 +576,11 @@ func transferAssetState(ctx contractapi.TransactionContextInterface, asset *Asse
 	if err != nil {
 		return err
 	}
-	assetReceipt := receipt{
-		price:     price,
-		timestamp: timestamp,
+	assetReceipt := Receipt{
+		AssetID:   asset.ID,
+		TxID:      ctx.GetStub().GetTxID(),
+		Price:     price,
+		Timestamp: timestamp,
 	}
 	receipt, err := json.Marshal(assetReceipt)
 	if err != nil {
@@ -486,6 +626,61 @@ func getClientOrgID(ctx contractapi.TransactionContextInterface, verifyOrg bool)
 	return clientOrgID, nil
 }
 
+// getClientIDHash returns the hex encoded SHA256 hash of the decoded ID of the submitting client identity.
+// The hash is not salted, so it does not keep the identity of owners private: any org that can
+// guess the ID of a client, which is made of the subject and issuer of its certificate, can
+// compare its hash with the owner of an asset.
+func getClientIDHash(ctx contractapi.TransactionContextInterface) (string, error) {
+	b64ID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to read clientID: %v", err)
+	}
+	decodeID, err := base64.StdEncoding.DecodeString(b64ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to base64 decode clientID: %v", err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(decodeID)), nil
+}
+
+// readBuyerIdentity reads the client that agreed to buy an asset, or returns nil if there is none
+func readBuyerIdentity(ctx contractapi.TransactionContextInterface, buyerKey string) (*buyerIdentity, error) {
+	buyerJSON, err := ctx.GetStub().GetState(buyerKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read buyer identity: %v", err)
+	}
+	if buyerJSON == nil {
+		return nil, nil
+	}
+
+	var buyer *buyerIdentity
+	err = json.Unmarshal(buyerJSON, &buyer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal buyer identity: %v", err)
+	}
+
+	return buyer, nil
+}
+
+// verifyClientIsOwner checks that the client identity owns the asset, so that clients of the
+// owning org cannot act on assets owned by other identities of the same org.
+// Assets created before identity ownership was introduced are only owned by an org.
+func verifyClientIsOwner(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	if asset.Owner == "" {
+		return nil
+	}
+
+	clientIDHash, err := getClientIDHash(ctx)
+	if err != nil {
+		return err
+	}
+
+	if clientIDHash != asset.Owner {
+		return fmt.Errorf("client does not own asset %s", asset.ID)
+	}
+
+	return nil
+}
+
 // verifyClientOrgMatchesPeerOrg checks the client org id matches the peer org id.
 func verifyClientOrgMatchesPeerOrg(clientOrgID string) error {
 	peerOrgID, err := shim.GetMSPID()
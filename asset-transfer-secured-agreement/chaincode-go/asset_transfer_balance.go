@@ -0,0 +1,171 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const typeOrgBalance = "balance"
+
+// Balance is the amount of funds an organization holds in the chaincode for
+// settling asset transfers on the ledger
+type Balance struct {
+	ObjectType string `json:"objectType"`
+	OrgID      string `json:"orgID"`
+	Amount     int    `json:"amount"`
+}
+
+// issuerMSP is the organization that issues the funds used to settle asset transfers.
+// This sample assumes Org1 is the settlement bank with the privilege to deposit funds
+const issuerMSP = "Org1MSP"
+
+// DepositFunds credits the balance of an organization with the given amount. Only clients
+// of the issuer organization can deposit funds, so that organizations cannot credit their
+// own balance. In a production scenario the deposit would be backed by a payment into a
+// settlement account outside of the ledger.
+func (s *SmartContract) DepositFunds(ctx contractapi.TransactionContextInterface, orgID string, amount int) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be a positive integer")
+	}
+
+	// the credited org endorses the deposit as well, so the org of the client is not
+	// verified against the org of the peer
+	clientOrgID, err := getClientOrgID(ctx, false)
+	if err != nil {
+		return fmt.Errorf("failed to get verified OrgID: %v", err)
+	}
+	if clientOrgID != issuerMSP {
+		return fmt.Errorf("client of org %s is not authorized to deposit funds", clientOrgID)
+	}
+
+	balance, err := readBalance(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	balance.Amount += amount
+
+	return putBalance(ctx, balance)
+}
+
+// WithdrawFunds debits the client org's balance with the given amount
+func (s *SmartContract) WithdrawFunds(ctx contractapi.TransactionContextInterface, amount int) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be a positive integer")
+	}
+
+	clientOrgID, err := getClientOrgID(ctx, true)
+	if err != nil {
+		return fmt.Errorf("failed to get verified OrgID: %v", err)
+	}
+
+	balance, err := readBalance(ctx, clientOrgID)
+	if err != nil {
+		return err
+	}
+	if balance.Amount < amount {
+		return fmt.Errorf("balance of %s is %d, cannot withdraw %d", clientOrgID, balance.Amount, amount)
+	}
+	balance.Amount -= amount
+
+	return putBalance(ctx, balance)
+}
+
+// GetBalance returns the balance of an organization
+func (s *SmartContract) GetBalance(ctx contractapi.TransactionContextInterface, orgID string) (*Balance, error) {
+	return readBalance(ctx, orgID)
+}
+
+// settlePayment moves the price of a transferred asset from the buyer org's balance to the
+// seller org's balance. It is called in the same transaction that transfers the asset, so
+// that the asset and the payment are settled together or not at all. When the asset is
+// transferred between two clients of the same org, the payment stays within the org and no
+// balance changes.
+func settlePayment(ctx contractapi.TransactionContextInterface, buyerOrgID string, sellerOrgID string, price int) error {
+	if price <= 0 {
+		return fmt.Errorf("price must be a positive integer for delivery versus payment")
+	}
+	if buyerOrgID == sellerOrgID {
+		return nil
+	}
+
+	buyerBalance, err := readBalance(ctx, buyerOrgID)
+	if err != nil {
+		return err
+	}
+	if buyerBalance.Amount < price {
+		return fmt.Errorf("balance of buyer %s is %d, which is less than the price %d", buyerOrgID, buyerBalance.Amount, price)
+	}
+
+	sellerBalance, err := readBalance(ctx, sellerOrgID)
+	if err != nil {
+		return err
+	}
+
+	buyerBalance.Amount -= price
+	sellerBalance.Amount += price
+
+	err = putBalance(ctx, buyerBalance)
+	if err != nil {
+		return fmt.Errorf("failed to debit buyer: %v", err)
+	}
+
+	err = putBalance(ctx, sellerBalance)
+	if err != nil {
+		return fmt.Errorf("failed to credit seller: %v", err)
+	}
+
+	return nil
+}
+
+// readBalance reads the balance of an organization, an organization that never
+// deposited funds has a zero balance
+func readBalance(ctx contractapi.TransactionContextInterface, orgID string) (*Balance, error) {
+	balanceKey, err := ctx.GetStub().CreateCompositeKey(typeOrgBalance, []string{orgID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	balanceJSON, err := ctx.GetStub().GetState(balanceKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read balance from world state: %v", err)
+	}
+	if balanceJSON == nil {
+		return &Balance{ObjectType: typeOrgBalance, OrgID: orgID}, nil
+	}
+
+	var balance *Balance
+	err = json.Unmarshal(balanceJSON, &balance)
+	if err != nil {
+		return nil, err
+	}
+
+	return balance, nil
+}
+
+// putBalance writes the balance of an organization and makes the organization
+// the endorser of future updates to it
+func putBalance(ctx contractapi.TransactionContextInterface, balance *Balance) error {
+	balanceKey, err := ctx.GetStub().CreateCompositeKey(typeOrgBalance, []string{balance.OrgID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	balanceJSON, err := json.Marshal(balance)
+	if err != nil {
+		return fmt.Errorf("failed to marshal balance: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(balanceKey, balanceJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put balance in public data: %v", err)
+	}
+
+	// Both the debited and the credited org have to endorse changes to their balance
+	return setAssetStateBasedEndorsement(ctx, balanceKey, balance.OrgID)
+}
@@ -0,0 +1,68 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	buyerOrg  = "Org2MSP"
+	sellerOrg = "Org3MSP"
+)
+
+// prepBalances returns a transaction context backed by an in-memory ledger that holds
+// the given balances
+func prepBalances(t *testing.T, balances map[string]int) *contractapi.TransactionContext {
+	stub := shimtest.NewMockStub("secured", nil)
+	stub.MockTransactionStart("tx1")
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+
+	for orgID, amount := range balances {
+		err := putBalance(ctx, &Balance{ObjectType: typeOrgBalance, OrgID: orgID, Amount: amount})
+		require.NoError(t, err)
+	}
+	return ctx
+}
+
+func requireBalance(t *testing.T, ctx contractapi.TransactionContextInterface, orgID string, amount int) {
+	balance, err := readBalance(ctx, orgID)
+	require.NoError(t, err)
+	require.Equal(t, amount, balance.Amount)
+}
+
+func TestSettlePaymentConservesBalances(t *testing.T) {
+	ctx := prepBalances(t, map[string]int{buyerOrg: 500, sellerOrg: 100})
+
+	err := settlePayment(ctx, buyerOrg, sellerOrg, 110)
+	require.NoError(t, err)
+	requireBalance(t, ctx, buyerOrg, 390)
+	requireBalance(t, ctx, sellerOrg, 210)
+}
+
+func TestSettlePaymentWithinOrgKeepsBalance(t *testing.T) {
+	ctx := prepBalances(t, map[string]int{buyerOrg: 500})
+
+	err := settlePayment(ctx, buyerOrg, buyerOrg, 110)
+	require.NoError(t, err)
+	requireBalance(t, ctx, buyerOrg, 500)
+}
+
+func TestSettlePaymentRejectsInsufficientBalance(t *testing.T) {
+	ctx := prepBalances(t, map[string]int{buyerOrg: 100, sellerOrg: 100})
+
+	err := settlePayment(ctx, buyerOrg, sellerOrg, 110)
+	require.EqualError(t, err, "balance of buyer Org2MSP is 100, which is less than the price 110")
+	requireBalance(t, ctx, buyerOrg, 100)
+	requireBalance(t, ctx, sellerOrg, 100)
+
+	err = settlePayment(ctx, buyerOrg, sellerOrg, 0)
+	require.EqualError(t, err, "price must be a positive integer for delivery versus payment")
+}
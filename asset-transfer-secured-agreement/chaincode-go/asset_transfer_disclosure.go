@@ -0,0 +1,341 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// The salt property is used to derive a salt for each property and is never disclosed.
+// It must be a hex encoded string of at least minSaltLength random bytes.
+const (
+	propertiesSaltField = "salt"
+	minSaltLength       = 16
+)
+
+// Domain separation prefixes for the hashes of the properties Merkle tree
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// ProofNode is a sibling hash on the path from a property to the Merkle root.
+// Left is true when the sibling is the left child of the parent node.
+type ProofNode struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+// PropertyDisclosure is a single private property disclosed together with
+// the proof that it is included in the asset's properties root hash
+type PropertyDisclosure struct {
+	Field string      `json:"field"`
+	Value string      `json:"value"` // Value is the compact JSON encoding of the property value
+	Salt  string      `json:"salt"`
+	Proof []ProofNode `json:"proof"`
+}
+
+// AssetPropertiesDisclosure is the set of properties that a seller discloses to a buyer
+type AssetPropertiesDisclosure struct {
+	AssetID        string               `json:"assetID"`
+	PropertiesRoot string               `json:"propertiesRoot"`
+	Properties     []PropertyDisclosure `json:"properties"`
+}
+
+// propertyLeaf is a single field of the private properties, hashed into a Merkle tree leaf
+type propertyLeaf struct {
+	field string
+	value []byte
+	salt  []byte
+}
+
+// GetAssetPropertiesDisclosure allows the owner org to disclose selected fields of the
+// private asset properties, with an inclusion proof for each field against the properties
+// root hash that is recorded on the public asset. The disclosure is shared with the buyer
+// off-chain and checked with VerifyAssetPropertiesDisclosure.
+func (s *SmartContract) GetAssetPropertiesDisclosure(ctx contractapi.TransactionContextInterface, assetID string, fields []string) (*AssetPropertiesDisclosure, error) {
+	// In this scenario, client is only authorized to read/write private data from its own peer.
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	immutablePropertiesJSON, err := ctx.GetStub().GetPrivateData(collection, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset private properties from client org's collection: %v", err)
+	}
+	if immutablePropertiesJSON == nil {
+		return nil, fmt.Errorf("asset private details does not exist in client org's collection: %s", assetID)
+	}
+
+	leaves, err := buildPropertyLeaves(immutablePropertiesJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	leafHashes := make([][]byte, len(leaves))
+	leafIndex := make(map[string]int)
+	for i, leaf := range leaves {
+		leafHashes[i] = hashPropertyLeaf(leaf)
+		leafIndex[leaf.field] = i
+	}
+
+	disclosure := &AssetPropertiesDisclosure{
+		AssetID:        assetID,
+		PropertiesRoot: hex.EncodeToString(merkleRoot(leafHashes)),
+		Properties:     []PropertyDisclosure{},
+	}
+	for _, field := range fields {
+		i, ok := leafIndex[field]
+		if !ok {
+			return nil, fmt.Errorf("asset %s does not have a private property %s", assetID, field)
+		}
+
+		disclosure.Properties = append(disclosure.Properties, PropertyDisclosure{
+			Field: field,
+			Value: string(leaves[i].value),
+			Salt:  hex.EncodeToString(leaves[i].salt),
+			Proof: merkleProof(leafHashes, i),
+		})
+	}
+
+	return disclosure, nil
+}
+
+// VerifyAssetPropertiesDisclosure allows a buyer to verify the properties that the seller disclosed
+// against the properties root hash recorded on the public asset, without receiving every property.
+// The AssetPropertiesDisclosure JSON is passed in the transient field "asset_properties_disclosure".
+// It returns false if the disclosure is for another asset, does not disclose any property, or if a
+// disclosed property does not match the properties root hash.
+func (s *SmartContract) VerifyAssetPropertiesDisclosure(ctx contractapi.TransactionContextInterface, assetID string) (bool, error) {
+	transMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return false, fmt.Errorf("error getting transient: %v", err)
+	}
+
+	// Disclosed properties must be retrieved from the transient field as they are private
+	disclosureJSON, ok := transMap["asset_properties_disclosure"]
+	if !ok {
+		return false, fmt.Errorf("asset_properties_disclosure key not found in the transient map")
+	}
+
+	var disclosure AssetPropertiesDisclosure
+	err = json.Unmarshal(disclosureJSON, &disclosure)
+	if err != nil {
+		return false, fmt.Errorf("failed to unmarshal disclosure JSON: %v", err)
+	}
+	if disclosure.AssetID != assetID || len(disclosure.Properties) == 0 {
+		return false, nil
+	}
+
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get asset: %v", err)
+	}
+	if asset.PropertiesRoot == "" {
+		return false, fmt.Errorf("asset %s does not have a properties root hash", assetID)
+	}
+
+	onChainRoot, err := hex.DecodeString(asset.PropertiesRoot)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode properties root hash: %v", err)
+	}
+
+	for _, property := range disclosure.Properties {
+		calculatedRoot, err := verifyPropertyDisclosure(property)
+		if err != nil {
+			return false, err
+		}
+
+		if !bytes.Equal(calculatedRoot, onChainRoot) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// hasPropertiesSalt reports whether the private properties are a JSON object with a salt property
+func hasPropertiesSalt(immutablePropertiesJSON []byte) bool {
+	var properties map[string]json.RawMessage
+	err := json.Unmarshal(immutablePropertiesJSON, &properties)
+	if err != nil {
+		return false
+	}
+
+	_, ok := properties[propertiesSaltField]
+	return ok
+}
+
+// computePropertiesRoot returns the hex encoded Merkle root hash of the private properties
+func computePropertiesRoot(immutablePropertiesJSON []byte) (string, error) {
+	leaves, err := buildPropertyLeaves(immutablePropertiesJSON)
+	if err != nil {
+		return "", err
+	}
+
+	leafHashes := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		leafHashes[i] = hashPropertyLeaf(leaf)
+	}
+
+	return hex.EncodeToString(merkleRoot(leafHashes)), nil
+}
+
+// buildPropertyLeaves splits the private properties JSON object into leaves sorted by field name.
+// Each field gets its own salt derived from the salt property, so that disclosing one field does
+// not allow the values of the undisclosed fields to be guessed from their hashes in the proof.
+func buildPropertyLeaves(immutablePropertiesJSON []byte) ([]propertyLeaf, error) {
+	var properties map[string]json.RawMessage
+	err := json.Unmarshal(immutablePropertiesJSON, &properties)
+	if err != nil {
+		return nil, fmt.Errorf("asset properties must be a JSON object: %v", err)
+	}
+
+	rawSalt, ok := properties[propertiesSaltField]
+	if !ok {
+		return nil, fmt.Errorf("asset properties must include a %s property", propertiesSaltField)
+	}
+	var hexSalt string
+	err = json.Unmarshal(rawSalt, &hexSalt)
+	if err != nil {
+		return nil, fmt.Errorf("%s property must be a hex encoded string: %v", propertiesSaltField, err)
+	}
+	salt, err := hex.DecodeString(hexSalt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s property: %v", propertiesSaltField, err)
+	}
+	if len(salt) < minSaltLength {
+		return nil, fmt.Errorf("%s property must be at least %d random bytes", propertiesSaltField, minSaltLength)
+	}
+
+	fields := make([]string, 0, len(properties))
+	for field := range properties {
+		if field != propertiesSaltField {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	leaves := make([]propertyLeaf, len(fields))
+	for i, field := range fields {
+		var value bytes.Buffer
+		err = json.Compact(&value, properties[field])
+		if err != nil {
+			return nil, fmt.Errorf("failed to compact property %s: %v", field, err)
+		}
+
+		fieldSalt := sha256.Sum256(append(append(append([]byte{}, salt...), 0x00), field...))
+		leaves[i] = propertyLeaf{
+			field: field,
+			value: value.Bytes(),
+			salt:  fieldSalt[:],
+		}
+	}
+
+	return leaves, nil
+}
+
+// verifyPropertyDisclosure returns the Merkle root hash calculated from a disclosed property and its proof
+func verifyPropertyDisclosure(property PropertyDisclosure) ([]byte, error) {
+	salt, err := hex.DecodeString(property.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode salt of property %s: %v", property.Field, err)
+	}
+
+	var value bytes.Buffer
+	err = json.Compact(&value, []byte(property.Value))
+	if err != nil {
+		return nil, fmt.Errorf("value of property %s is not valid JSON: %v", property.Field, err)
+	}
+
+	hash := hashPropertyLeaf(propertyLeaf{field: property.Field, value: value.Bytes(), salt: salt})
+	for _, node := range property.Proof {
+		sibling, err := hex.DecodeString(node.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode proof of property %s: %v", property.Field, err)
+		}
+		if node.Left {
+			hash = hashMerkleNode(sibling, hash)
+		} else {
+			hash = hashMerkleNode(hash, sibling)
+		}
+	}
+
+	return hash, nil
+}
+
+// hashPropertyLeaf hashes the length prefixed field name, value and salt of a property
+func hashPropertyLeaf(leaf propertyLeaf) []byte {
+	hash := sha256.New()
+	hash.Write([]byte{merkleLeafPrefix})
+	for _, part := range [][]byte{[]byte(leaf.field), leaf.value, leaf.salt} {
+		length := make([]byte, 4)
+		binary.BigEndian.PutUint32(length, uint32(len(part)))
+		hash.Write(length)
+		hash.Write(part)
+	}
+	return hash.Sum(nil)
+}
+
+func hashMerkleNode(left []byte, right []byte) []byte {
+	hash := sha256.New()
+	hash.Write([]byte{merkleNodePrefix})
+	hash.Write(left)
+	hash.Write(right)
+	return hash.Sum(nil)
+}
+
+// merkleRoot returns the root hash of the tree over the leaf hashes
+func merkleRoot(level [][]byte) []byte {
+	if len(level) == 0 {
+		return sha256.New().Sum(nil)
+	}
+
+	for len(level) > 1 {
+		level = nextMerkleLevel(level)
+	}
+
+	return level[0]
+}
+
+// merkleProof returns the sibling hashes on the path from the leaf at index to the root
+func merkleProof(level [][]byte, index int) []ProofNode {
+	proof := []ProofNode{}
+	for len(level) > 1 {
+		if index%2 == 1 {
+			proof = append(proof, ProofNode{Hash: hex.EncodeToString(level[index-1]), Left: true})
+		} else if index+1 < len(level) {
+			proof = append(proof, ProofNode{Hash: hex.EncodeToString(level[index+1]), Left: false})
+		}
+
+		level = nextMerkleLevel(level)
+		index /= 2
+	}
+
+	return proof
+}
+
+// nextMerkleLevel hashes pairs of nodes into their parents. A node without
+// a sibling is promoted to the next level unchanged.
+func nextMerkleLevel(level [][]byte) [][]byte {
+	var next [][]byte
+	for i := 0; i < len(level); i += 2 {
+		if i+1 < len(level) {
+			next = append(next, hashMerkleNode(level[i], level[i+1]))
+		} else {
+			next = append(next, level[i])
+		}
+	}
+	return next
+}
@@ -0,0 +1,54 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const saltedProperties = `{"object_type":"asset_properties","color":"blue","size":35,"salt":"a94a8fe5ccb19ba61c4c0873d391e987"}`
+
+func TestHasPropertiesSalt(t *testing.T) {
+	require.True(t, hasPropertiesSalt([]byte(saltedProperties)))
+	require.False(t, hasPropertiesSalt([]byte(`{"object_type":"asset_properties","color":"blue"}`)))
+	require.False(t, hasPropertiesSalt([]byte("not a JSON object")))
+}
+
+func TestComputePropertiesRootRejectsShortSalt(t *testing.T) {
+	_, err := computePropertiesRoot([]byte(`{"color":"blue","salt":"3432"}`))
+	require.EqualError(t, err, "salt property must be at least 16 random bytes")
+}
+
+func TestPropertyDisclosureMatchesRoot(t *testing.T) {
+	root, err := computePropertiesRoot([]byte(saltedProperties))
+	require.NoError(t, err)
+
+	leaves, err := buildPropertyLeaves([]byte(saltedProperties))
+	require.NoError(t, err)
+	leafHashes := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		leafHashes[i] = hashPropertyLeaf(leaf)
+	}
+
+	for i, leaf := range leaves {
+		property := PropertyDisclosure{
+			Field: leaf.field,
+			Value: string(leaf.value),
+			Salt:  hex.EncodeToString(leaf.salt),
+			Proof: merkleProof(leafHashes, i),
+		}
+		calculatedRoot, err := verifyPropertyDisclosure(property)
+		require.NoError(t, err)
+		require.Equal(t, root, hex.EncodeToString(calculatedRoot))
+
+		property.Value = `"red"`
+		calculatedRoot, err = verifyPropertyDisclosure(property)
+		require.NoError(t, err)
+		require.NotEqual(t, root, hex.EncodeToString(calculatedRoot))
+	}
+}
@@ -0,0 +1,313 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	typeAssetOffer         = "O"
+	typeAssetOfferSequence = "OS"
+)
+
+// Offer is a price that one side posted during the negotiation of an asset sale
+type Offer struct {
+	Sequence  int       `json:"sequence"`
+	PriceType string    `json:"priceType"`
+	Agreement Agreement `json:"agreement"`
+}
+
+// offerSequence tracks the number of offers an org has posted for an asset.
+// It is kept in public state so that superseded offers can be removed from the
+// implicit collections of both orgs when the asset is transferred.
+type offerSequence struct {
+	Sequence int `json:"sequence"`
+}
+
+// NegotiationStatus reports the latest offer sequence numbers of the seller and
+// the buyer of an asset, and whether their latest offers are the same price
+type NegotiationStatus struct {
+	AssetID        string `json:"assetID"`
+	SellerOrgID    string `json:"sellerOrgID"`
+	BuyerOrgID     string `json:"buyerOrgID"`
+	SellerSequence int    `json:"sellerSequence"`
+	BuyerSequence  int    `json:"buyerSequence"`
+	PricesMatch    bool   `json:"pricesMatch"`
+}
+
+// QueryNegotiationStatus compares the hashes of the latest seller and buyer offers for an asset.
+// The prices themselves remain private, only whether they match is revealed.
+// The status can only be queried by the seller, or by a buyer that posted an offer for the asset.
+func (s *SmartContract) QueryNegotiationStatus(ctx contractapi.TransactionContextInterface, assetID string, buyerOrgID string) (*NegotiationStatus, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOrgID, err := getClientOrgID(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verified OrgID: %v", err)
+	}
+
+	sellerSequence, err := readOfferSequence(ctx, assetID, asset.OwnerOrg, typeAssetForSale)
+	if err != nil {
+		return nil, err
+	}
+	buyerSequence, err := readOfferSequence(ctx, assetID, buyerOrgID, typeAssetBid)
+	if err != nil {
+		return nil, err
+	}
+
+	// A buyer org is only a party to the negotiation once it posted an offer, so that
+	// other orgs cannot compare their prices by passing their own org as the buyer
+	if clientOrgID != asset.OwnerOrg && (clientOrgID != buyerOrgID || buyerSequence == 0) {
+		return nil, fmt.Errorf("negotiation status of asset %s can only be queried by the seller or a buyer with an offer", assetID)
+	}
+
+	sellerPriceHash, err := getPriceHash(ctx, assetID, asset.OwnerOrg, typeAssetForSale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seller price hash: %v", err)
+	}
+	buyerPriceHash, err := getPriceHash(ctx, assetID, buyerOrgID, typeAssetBid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get buyer price hash: %v", err)
+	}
+
+	return &NegotiationStatus{
+		AssetID:        assetID,
+		SellerOrgID:    asset.OwnerOrg,
+		BuyerOrgID:     buyerOrgID,
+		SellerSequence: sellerSequence,
+		BuyerSequence:  buyerSequence,
+		PricesMatch:    sellerPriceHash != nil && bytes.Equal(sellerPriceHash, buyerPriceHash),
+	}, nil
+}
+
+// QueryAssetOffers returns all offers that the client's org posted for an asset,
+// from the client org's implicit private data collection
+func (s *SmartContract) QueryAssetOffers(ctx contractapi.TransactionContextInterface, assetID string) ([]Offer, error) {
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	offersIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(collection, typeAssetOffer, []string{assetID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from private data collection: %v", err)
+	}
+	defer offersIterator.Close()
+
+	var offers []Offer
+	for offersIterator.HasNext() {
+		resp, err := offersIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(resp.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(keyParts) < 3 {
+			return nil, fmt.Errorf("unexpected offer key %s", resp.Key)
+		}
+		sequence, err := strconv.Atoi(keyParts[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid offer sequence %s: %v", keyParts[2], err)
+		}
+
+		var agreement Agreement
+		err = json.Unmarshal(resp.Value, &agreement)
+		if err != nil {
+			return nil, err
+		}
+
+		offers = append(offers, Offer{
+			Sequence:  sequence,
+			PriceType: keyParts[1],
+			Agreement: agreement,
+		})
+	}
+
+	return offers, nil
+}
+
+// recordOffer keeps the posted price as the next offer in the org's negotiation history
+func recordOffer(ctx contractapi.TransactionContextInterface, assetID string, orgID string, priceType string, price []byte) error {
+	sequence, err := readOfferSequence(ctx, assetID, orgID, priceType)
+	if err != nil {
+		return err
+	}
+	sequence++
+
+	offerKey, err := createOfferKey(ctx, assetID, priceType, sequence)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutPrivateData(buildCollectionName(orgID), offerKey, price)
+	if err != nil {
+		return fmt.Errorf("failed to put offer: %v", err)
+	}
+
+	sequenceKey, err := ctx.GetStub().CreateCompositeKey(typeAssetOfferSequence, []string{assetID, orgID, priceType})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	sequenceJSON, err := json.Marshal(offerSequence{Sequence: sequence})
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer sequence: %v", err)
+	}
+	err = ctx.GetStub().PutState(sequenceKey, sequenceJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put offer sequence: %v", err)
+	}
+
+	return nil
+}
+
+// deleteOffers removes all offers an org posted for an asset, along with their sequence counter.
+// The offer keys are derived from the public sequence counter, so the offers can be deleted
+// without reading the org's implicit private data collection.
+func deleteOffers(ctx contractapi.TransactionContextInterface, assetID string, orgID string, priceType string) error {
+	sequence, err := readOfferSequence(ctx, assetID, orgID, priceType)
+	if err != nil {
+		return err
+	}
+
+	collection := buildCollectionName(orgID)
+	for i := 1; i <= sequence; i++ {
+		offerKey, err := createOfferKey(ctx, assetID, priceType, i)
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().DelPrivateData(collection, offerKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete offer %d: %v", i, err)
+		}
+	}
+
+	sequenceKey, err := ctx.GetStub().CreateCompositeKey(typeAssetOfferSequence, []string{assetID, orgID, priceType})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(sequenceKey)
+}
+
+// DeleteAssetOffers removes the offers that the client's org posted for an asset from the
+// client org's implicit private data collection. When an asset is transferred, the offers
+// of the seller and the buyer are removed, but the offers of other buyers can only be
+// removed by the buyers themselves, as only the peers of an org can write to its
+// implicit collection.
+func (s *SmartContract) DeleteAssetOffers(ctx contractapi.TransactionContextInterface, assetID string) error {
+	clientOrgID, err := getClientOrgID(ctx, true)
+	if err != nil {
+		return fmt.Errorf("failed to get verified OrgID: %v", err)
+	}
+	collection := buildCollectionName(clientOrgID)
+
+	offersIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(collection, typeAssetOffer, []string{assetID})
+	if err != nil {
+		return fmt.Errorf("failed to read from private data collection: %v", err)
+	}
+	defer offersIterator.Close()
+
+	for offersIterator.HasNext() {
+		resp, err := offersIterator.Next()
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().DelPrivateData(collection, resp.Key)
+		if err != nil {
+			return fmt.Errorf("failed to delete offer: %v", err)
+		}
+	}
+
+	for _, priceType := range []string{typeAssetForSale, typeAssetBid} {
+		sequenceKey, err := ctx.GetStub().CreateCompositeKey(typeAssetOfferSequence, []string{assetID, clientOrgID, priceType})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().DelState(sequenceKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete offer sequence: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// deleteOfferSequences removes the offer counters of all orgs for an asset
+func deleteOfferSequences(ctx contractapi.TransactionContextInterface, assetID string) error {
+	sequencesIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(typeAssetOfferSequence, []string{assetID})
+	if err != nil {
+		return fmt.Errorf("failed to read offer sequences from world state: %v", err)
+	}
+	defer sequencesIterator.Close()
+
+	for sequencesIterator.HasNext() {
+		resp, err := sequencesIterator.Next()
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().DelState(resp.Key)
+		if err != nil {
+			return fmt.Errorf("failed to delete offer sequence: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// readOfferSequence returns the sequence number of the latest offer an org posted for an asset
+func readOfferSequence(ctx contractapi.TransactionContextInterface, assetID string, orgID string, priceType string) (int, error) {
+	sequenceKey, err := ctx.GetStub().CreateCompositeKey(typeAssetOfferSequence, []string{assetID, orgID, priceType})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	sequenceJSON, err := ctx.GetStub().GetState(sequenceKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read offer sequence from world state: %v", err)
+	}
+	if sequenceJSON == nil {
+		return 0, nil
+	}
+
+	var sequence offerSequence
+	err = json.Unmarshal(sequenceJSON, &sequence)
+	if err != nil {
+		return 0, err
+	}
+
+	return sequence.Sequence, nil
+}
+
+// getPriceHash returns the hash of the latest price an org agreed to, or nil if there is none
+func getPriceHash(ctx contractapi.TransactionContextInterface, assetID string, orgID string, priceType string) ([]byte, error) {
+	assetPriceKey, err := ctx.GetStub().CreateCompositeKey(priceType, []string{assetID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().GetPrivateDataHash(buildCollectionName(orgID), assetPriceKey)
+}
+
+// createOfferKey zero pads the sequence number so that offers are returned in order by range queries
+func createOfferKey(ctx contractapi.TransactionContextInterface, assetID string, priceType string, sequence int) (string, error) {
+	offerKey, err := ctx.GetStub().CreateCompositeKey(typeAssetOffer, []string{assetID, priceType, fmt.Sprintf("%010d", sequence)})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return offerKey, nil
+}
@@ -5,8 +5,11 @@
 package main
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/golang/protobuf/ptypes"
@@ -24,6 +27,9 @@ type Agreement struct {
 	ID      string `json:"asset_id"`
 	Price   int    `json:"price"`
 	TradeID string `json:"trade_id"`
+	// DeliveryVersusPayment settles the price from the buyer's balance to the seller's
+	// balance in the same transaction that transfers the asset
+	DeliveryVersusPayment bool `json:"delivery_versus_payment,omitempty"`
 }
 
 // ReadAsset returns the public asset data
@@ -139,6 +145,161 @@ func queryAgreementsByType(ctx contractapi.TransactionContextInterface, agreeTyp
 	return agreements, nil
 }
 
+// GetSalesReceipts returns the receipts of the client org's sales with a timestamp between
+// startDate and endDate. Dates are RFC3339 timestamps or YYYY-MM-DD days, a date only endDate
+// includes the whole day. An empty startDate or endDate leaves the range open on that side.
+func (s *SmartContract) GetSalesReceipts(ctx contractapi.TransactionContextInterface, startDate string, endDate string) ([]Receipt, error) {
+	return queryReceiptsByType(ctx, typeAssetSaleReceipt, startDate, endDate)
+}
+
+// GetPurchaseReceipts returns the receipts of the client org's purchases with a timestamp
+// between startDate and endDate, see GetSalesReceipts for the date format.
+func (s *SmartContract) GetPurchaseReceipts(ctx contractapi.TransactionContextInterface, startDate string, endDate string) ([]Receipt, error) {
+	return queryReceiptsByType(ctx, typeAssetBuyReceipt, startDate, endDate)
+}
+
+// ExportReceipts returns the client org's sales and purchase receipts with a timestamp between
+// startDate and endDate, formatted as "csv" or "json" for reconciliation outside of the ledger.
+// Each exported record is labelled with the side of the trade, "sale" or "purchase".
+func (s *SmartContract) ExportReceipts(ctx contractapi.TransactionContextInterface, startDate string, endDate string, format string) (string, error) {
+	type exportedReceipt struct {
+		Type string `json:"type"`
+		Receipt
+	}
+
+	var records []exportedReceipt
+	for _, receiptType := range []struct {
+		label      string
+		objectType string
+	}{
+		{"sale", typeAssetSaleReceipt},
+		{"purchase", typeAssetBuyReceipt},
+	} {
+		receipts, err := queryReceiptsByType(ctx, receiptType.objectType, startDate, endDate)
+		if err != nil {
+			return "", err
+		}
+		for _, receipt := range receipts {
+			records = append(records, exportedReceipt{Type: receiptType.label, Receipt: receipt})
+		}
+	}
+
+	switch format {
+	case "json":
+		if records == nil {
+			records = []exportedReceipt{}
+		}
+		recordsJSON, err := json.Marshal(records)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal receipts: %v", err)
+		}
+		return string(recordsJSON), nil
+	case "csv":
+		var buffer bytes.Buffer
+		writer := csv.NewWriter(&buffer)
+		err := writer.Write([]string{"type", "assetID", "txID", "price", "timestamp"})
+		if err != nil {
+			return "", err
+		}
+		for _, record := range records {
+			err = writer.Write([]string{
+				record.Type,
+				record.AssetID,
+				record.TxID,
+				strconv.Itoa(record.Price),
+				record.Timestamp.Format(time.RFC3339),
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return "", fmt.Errorf("failed to write receipts as CSV: %v", err)
+		}
+		return buffer.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported export format %s, must be csv or json", format)
+	}
+}
+
+// queryReceiptsByType returns the receipts of a type from the client org's implicit collection
+// with a timestamp between startDate and endDate
+func queryReceiptsByType(ctx contractapi.TransactionContextInterface, receiptType string, startDate string, endDate string) ([]Receipt, error) {
+	start, err := parseReceiptDate(startDate, false)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseReceiptDate(endDate, true)
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	receiptsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(collection, receiptType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from private data collection: %v", err)
+	}
+	defer receiptsIterator.Close()
+
+	var receipts []Receipt
+	for receiptsIterator.HasNext() {
+		resp, err := receiptsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var receipt Receipt
+		err = json.Unmarshal(resp.Value, &receipt)
+		if err != nil {
+			return nil, err
+		}
+
+		if !start.IsZero() && receipt.Timestamp.Before(start) {
+			continue
+		}
+		if !end.IsZero() && !receipt.Timestamp.Before(end) {
+			continue
+		}
+
+		receipts = append(receipts, receipt)
+	}
+
+	return receipts, nil
+}
+
+// parseReceiptDate parses an RFC3339 timestamp or a YYYY-MM-DD day. A day used as the end of
+// a range is moved to the start of the next day, so that the whole day is included.
+// An empty date returns the zero time.
+func parseReceiptDate(date string, isEnd bool) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, date)
+	if err == nil {
+		if isEnd {
+			// The end timestamp itself is included in the range
+			timestamp = timestamp.Add(time.Nanosecond)
+		}
+		return timestamp, nil
+	}
+
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %s, must be RFC3339 or YYYY-MM-DD", date)
+	}
+	if isEnd {
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return day, nil
+}
+
 // QueryAssetHistory returns the chain of custody for a asset since issuance
 func (s *SmartContract) QueryAssetHistory(ctx contractapi.TransactionContextInterface, assetID string) ([]QueryResult, error) {
 	resultsIterator, err := ctx.GetStub().GetHistoryForKey(assetID)
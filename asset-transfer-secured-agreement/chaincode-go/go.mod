@@ -6,5 +6,6 @@ require (
 	github.com/golang/protobuf v1.3.2
 	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200128192331-2d899240a7ed
 	github.com/hyperledger/fabric-contract-api-go v1.0.0
+	github.com/stretchr/testify v1.5.1
 	golang.org/x/tools v0.1.0 // indirect
 )
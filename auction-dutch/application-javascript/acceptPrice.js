@@ -0,0 +1,91 @@
+/*
+ * Copyright IBM Corp. All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+'use strict';
+
+const { Gateway, Wallets } = require('fabric-network');
+const path = require('path');
+const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString } = require('../../test-application/javascript/AppUtil.js');
+
+const myChannel = 'mychannel';
+const myChaincodeName = 'auction';
+
+async function acceptPrice (ccp, wallet, user, auctionID, quantity) {
+	try {
+		const gateway = new Gateway();
+		// connect using Discovery enabled
+
+		await gateway.connect(ccp,
+			{ wallet: wallet, identity: user, discovery: { enabled: true, asLocalhost: true } });
+
+		const network = await gateway.getNetwork(myChannel);
+		const contract = network.getContract(myChaincodeName);
+
+		console.log('\n--> Evaluate Transaction: query the current price of the clock');
+		const price = await contract.evaluateTransaction('QueryCurrentPrice', auctionID);
+		console.log('*** Result: Price: ' + price.toString());
+
+		// Query the auction to get the list of endorsing orgs.
+		const auctionString = await contract.evaluateTransaction('QueryAuction', auctionID);
+		const auctionJSON = JSON.parse(auctionString);
+
+		// the price is paid from the balance of the buyer, which is endorsed by the organization of the buyer
+		const orgs = auctionJSON.organizations.slice();
+		const buyerMSP = (await wallet.get(user)).mspId;
+		if (!orgs.includes(buyerMSP)) {
+			orgs.push(buyerMSP);
+		}
+
+		const statefulTxn = contract.createTransaction('AcceptPrice');
+		statefulTxn.setEndorsingOrganizations(...orgs);
+
+		console.log('\n--> Submit Transaction: buy units at the current price of the clock');
+		await statefulTxn.submit(auctionID, parseInt(quantity));
+		console.log('*** Result: committed');
+
+		console.log('\n--> Evaluate Transaction: query the updated auction');
+		const result = await contract.evaluateTransaction('QueryAuction', auctionID);
+		console.log('*** Result: Auction: ' + prettyJSONString(result.toString()));
+
+		gateway.disconnect();
+	} catch (error) {
+		console.error(`******** FAILED to accept price: ${error}`);
+	}
+}
+
+async function main () {
+	try {
+		if (process.argv[2] === undefined || process.argv[3] === undefined ||
+            process.argv[4] === undefined || process.argv[5] === undefined) {
+			console.log('Usage: node acceptPrice.js org userID auctionID quantity');
+			process.exit(1);
+		}
+
+		const org = process.argv[2];
+		const user = process.argv[3];
+		const auctionID = process.argv[4];
+		const quantity = process.argv[5];
+
+		if (org === 'Org1' || org === 'org1') {
+			const ccp = buildCCPOrg1();
+			const walletPath = path.join(__dirname, 'wallet/org1');
+			const wallet = await buildWallet(Wallets, walletPath);
+			await acceptPrice(ccp, wallet, user, auctionID, quantity);
+		} else if (org === 'Org2' || org === 'org2') {
+			const ccp = buildCCPOrg2();
+			const walletPath = path.join(__dirname, 'wallet/org2');
+			const wallet = await buildWallet(Wallets, walletPath);
+			await acceptPrice(ccp, wallet, user, auctionID, quantity);
+		} else {
+			console.log('Usage: node acceptPrice.js org userID auctionID quantity');
+			console.log('Org must be Org1 or Org2');
+		}
+	} catch (error) {
+		console.error(`******** FAILED to run the application: ${error}`);
+	}
+}
+
+main();
@@ -8,6 +8,7 @@
 
 const { Gateway, Wallets } = require('fabric-network');
 const path = require('path');
+const crypto = require('crypto');
 const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString } = require('../../test-application/javascript/AppUtil.js');
 
 const myChannel = 'mychannel';
@@ -28,7 +29,8 @@ async function bid (ccp, wallet, user, orgMSP, auctionID, quantity, price) {
 		const buyer = await contract.evaluateTransaction('GetSubmittingClientIdentity');
 		console.log('*** Result:  Buyer ID is ' + buyer.toString());
 
-		const bidData = { objectType: 'bid', quantity: parseInt(quantity), price: parseInt(price), org: orgMSP, buyer: buyer.toString() };
+		// the random salt prevents other organizations from guessing the price from the hash of the bid
+		const bidData = { objectType: 'bid', quantity: parseInt(quantity), price: parseInt(price), org: orgMSP, buyer: buyer.toString(), salt: crypto.randomBytes(32).toString('hex') };
 
 		const statefulTxn = contract.createTransaction('Bid');
 		statefulTxn.setEndorsingOrganizations(orgMSP);
@@ -13,7 +13,7 @@ const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString } = require('.
 const myChannel = 'mychannel';
 const myChaincodeName = 'auction';
 
-async function createAuction (ccp, wallet, user, auctionID, item, quantity, auditor) {
+async function createAuction (ccp, wallet, user, auctionID, item, quantity, biddingMinutes, revealMinutes, auditor, deposit, paymentMinutes) {
 	try {
 		const gateway = new Gateway();
 		// connect using Discovery enabled
@@ -24,10 +24,21 @@ async function createAuction (ccp, wallet, user, auctionID, item, quantity, audi
 		const network = await gateway.getNetwork(myChannel);
 		const contract = network.getContract(myChaincodeName);
 
+		// bids can be submitted until the bidding deadline, and revealed until the reveal deadline
+		const now = Date.now();
+		const biddingDeadline = new Date(now + parseInt(biddingMinutes) * 60000).toISOString();
+		const revealDeadline = new Date(now + (parseInt(biddingMinutes) + parseInt(revealMinutes)) * 60000).toISOString();
+
+		// the winners of an auction with a deposit need to pay before the payment deadline
+		let paymentDeadline = '';
+		if (parseInt(deposit) > 0) {
+			paymentDeadline = new Date(now + (parseInt(biddingMinutes) + parseInt(revealMinutes) + parseInt(paymentMinutes)) * 60000).toISOString();
+		}
+
 		const statefulTxn = contract.createTransaction('CreateAuction');
 
 		console.log('\n--> Submit Transaction: Propose a new auction');
-		await statefulTxn.submit(auctionID, item, parseInt(quantity), auditor);
+		await statefulTxn.submit(auctionID, item, parseInt(quantity), auditor, parseInt(deposit), biddingDeadline, revealDeadline, paymentDeadline);
 		console.log('*** Result: committed');
 
 		console.log('\n--> Evaluate Transaction: query the auction that was just created');
@@ -44,8 +55,9 @@ async function main () {
 	try {
 		if (process.argv[2] === undefined || process.argv[3] === undefined ||
             process.argv[4] === undefined || process.argv[5] === undefined ||
-            process.argv[6] === undefined) {
-			console.log('Usage: node createAuction.js org userID auctionID item quantity');
+            process.argv[6] === undefined || process.argv[7] === undefined ||
+            process.argv[8] === undefined) {
+			console.log('Usage: node createAuction.js org userID auctionID item quantity biddingMinutes revealMinutes [withAuditor|noAuditor] [deposit paymentMinutes]');
 			process.exit(1);
 		}
 
@@ -54,20 +66,29 @@ async function main () {
 		const auctionID = process.argv[4];
 		const item = process.argv[5];
 		const quantity = process.argv[6];
-		const auditor = process.argv[7];
+		const biddingMinutes = process.argv[7];
+		const revealMinutes = process.argv[8];
+		const auditor = process.argv[9];
+		const deposit = process.argv[10] === undefined ? '0' : process.argv[10];
+		const paymentMinutes = process.argv[11] === undefined ? '0' : process.argv[11];
+
+		if (parseInt(deposit) > 0 && !(parseInt(paymentMinutes) > 0)) {
+			console.log('An auction with a deposit requires the number of minutes the winners have to pay');
+			process.exit(1);
+		}
 
 		if (org === 'Org1' || org === 'org1') {
 			const ccp = buildCCPOrg1();
 			const walletPath = path.join(__dirname, 'wallet/org1');
 			const wallet = await buildWallet(Wallets, walletPath);
-			await createAuction(ccp, wallet, user, auctionID, item, quantity, auditor);
+			await createAuction(ccp, wallet, user, auctionID, item, quantity, biddingMinutes, revealMinutes, auditor, deposit, paymentMinutes);
 		} else if (org === 'Org2' || org === 'org2') {
 			const ccp = buildCCPOrg2();
 			const walletPath = path.join(__dirname, 'wallet/org2');
 			const wallet = await buildWallet(Wallets, walletPath);
-			await createAuction(ccp, wallet, user, auctionID, item, quantity, auditor);
+			await createAuction(ccp, wallet, user, auctionID, item, quantity, biddingMinutes, revealMinutes, auditor, deposit, paymentMinutes);
 		} else {
-			console.log('Usage: node createAuction.js org userID auctionID item quantity');
+			console.log('Usage: node createAuction.js org userID auctionID item quantity biddingMinutes revealMinutes [withAuditor|noAuditor] [deposit paymentMinutes]');
 			console.log('Org must be Org1 or Org2');
 		}
 	} catch (error) {
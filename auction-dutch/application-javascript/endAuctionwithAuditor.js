@@ -24,9 +24,21 @@ async function endAuction (ccp, wallet, org, user, auctionID) {
 		const network = await gateway.getNetwork(myChannel);
 		const contract = network.getContract(myChaincodeName);
 
+		const auctionString = await contract.evaluateTransaction('QueryAuction', auctionID);
+		const auctionJSON = JSON.parse(auctionString);
+
 		const statefulTxn = contract.createTransaction('EndAuction');
 
-		statefulTxn.setEndorsingOrganizations(org, 'Org3MSP');
+		// the deposits are settled on the balances of the bidders, which are endorsed by the organizations of the bidders
+		const orgs = [org, 'Org3MSP'];
+		if (auctionJSON.deposit > 0) {
+			for (const bidderOrg of auctionJSON.organizations) {
+				if (!orgs.includes(bidderOrg)) {
+					orgs.push(bidderOrg);
+				}
+			}
+		}
+		statefulTxn.setEndorsingOrganizations(...orgs);
 
 		console.log('\n--> Submit the transaction to end the auction');
 		await statefulTxn.submit(auctionID);
@@ -0,0 +1,93 @@
+/*
+ * Copyright IBM Corp. All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+'use strict';
+
+const { Gateway, Wallets } = require('fabric-network');
+const path = require('path');
+const { buildCCPOrg1, buildCCPOrg2, buildWallet } = require('../../test-application/javascript/AppUtil.js');
+
+const myChannel = 'mychannel';
+const myChaincodeName = 'auction';
+
+async function mint (ccp, wallet, user, amount) {
+	try {
+		// read the account of the user from the certificate of the user
+		const gateway = new Gateway();
+		await gateway.connect(ccp,
+			{ wallet: wallet, identity: user, discovery: { enabled: true, asLocalhost: true } });
+
+		let network = await gateway.getNetwork(myChannel);
+		let contract = network.getContract(myChaincodeName);
+
+		const identity = await contract.evaluateTransaction('WhoAmI');
+		const account = JSON.parse(identity.toString()).ID;
+		const accountMSP = JSON.parse(identity.toString()).mspID;
+		gateway.disconnect();
+
+		// the units can only be issued by an admin of the issuer organization, Org1
+		const issuerCcp = buildCCPOrg1();
+		const issuerWallet = await buildWallet(Wallets, path.join(__dirname, 'wallet/org1'));
+		const adminGateway = new Gateway();
+		await adminGateway.connect(issuerCcp,
+			{ wallet: issuerWallet, identity: 'admin', discovery: { enabled: true, asLocalhost: true } });
+
+		network = await adminGateway.getNetwork(myChannel);
+		contract = network.getContract(myChaincodeName);
+
+		console.log('\n--> Submit Transaction: issue units to the account of ' + user);
+		// the balance of the account is endorsed by the organization of the account
+		const statefulTxn = contract.createTransaction('Mint');
+		if (accountMSP === 'Org1MSP') {
+			statefulTxn.setEndorsingOrganizations('Org1MSP');
+		} else {
+			statefulTxn.setEndorsingOrganizations('Org1MSP', accountMSP);
+		}
+		await statefulTxn.submit(account, amount);
+		console.log('*** Result: committed');
+
+		console.log('\n--> Evaluate Transaction: query the balance of ' + user);
+		const result = await contract.evaluateTransaction('QueryBalance', account);
+		console.log('*** Result: Balance: ' + result.toString());
+
+		adminGateway.disconnect();
+	} catch (error) {
+		console.error(`******** FAILED to mint units: ${error}`);
+	}
+}
+
+async function main () {
+	try {
+		if (process.argv[2] === undefined || process.argv[3] === undefined ||
+            process.argv[4] === undefined) {
+			console.log('Usage: node mint.js org userID amount');
+			process.exit(1);
+		}
+
+		const org = process.argv[2];
+		const user = process.argv[3];
+		const amount = process.argv[4];
+
+		if (org === 'Org1' || org === 'org1') {
+			const ccp = buildCCPOrg1();
+			const walletPath = path.join(__dirname, 'wallet/org1');
+			const wallet = await buildWallet(Wallets, walletPath);
+			await mint(ccp, wallet, user, amount);
+		} else if (org === 'Org2' || org === 'org2') {
+			const ccp = buildCCPOrg2();
+			const walletPath = path.join(__dirname, 'wallet/org2');
+			const wallet = await buildWallet(Wallets, walletPath);
+			await mint(ccp, wallet, user, amount);
+		} else {
+			console.log('Usage: node mint.js org userID amount');
+			console.log('Org must be Org1 or Org2');
+		}
+	} catch (error) {
+		console.error(`******** FAILED to run the application: ${error}`);
+	}
+}
+
+main();
@@ -0,0 +1,88 @@
+/*
+ * Copyright IBM Corp. All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+'use strict';
+
+const { Gateway, Wallets } = require('fabric-network');
+const path = require('path');
+const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString } = require('../../test-application/javascript/AppUtil.js');
+
+const myChannel = 'mychannel';
+const myChaincodeName = 'auction';
+
+async function payAuction (ccp, wallet, user, auctionID, buyer) {
+	try {
+		const gateway = new Gateway();
+		// connect using Discovery enabled
+
+		await gateway.connect(ccp,
+			{ wallet: wallet, identity: user, discovery: { enabled: true, asLocalhost: true } });
+
+		const network = await gateway.getNetwork(myChannel);
+		const contract = network.getContract(myChaincodeName);
+
+		// the balances of the buyer and the seller are endorsed by the organizations of the auction
+		const auctionString = await contract.evaluateTransaction('QueryAuction', auctionID);
+		const auctionJSON = JSON.parse(auctionString);
+
+		if (buyer !== undefined) {
+			console.log('\n--> Submit Transaction: forfeit the deposits of the buyer after the payment deadline');
+			const statefulTxn = contract.createTransaction('ForfeitDeposit');
+			statefulTxn.setEndorsingOrganizations(...auctionJSON.organizations);
+			await statefulTxn.submit(auctionID, buyer);
+		} else {
+			console.log('\n--> Submit Transaction: pay for the items won in the auction');
+			const statefulTxn = contract.createTransaction('PayAuction');
+			statefulTxn.setEndorsingOrganizations(...auctionJSON.organizations);
+			await statefulTxn.submit(auctionID);
+			const identity = await contract.evaluateTransaction('WhoAmI');
+			buyer = JSON.parse(identity.toString()).ID;
+		}
+		console.log('*** Result: committed');
+
+		console.log('\n--> Evaluate Transaction: query the payment of the buyer');
+		const result = await contract.evaluateTransaction('QueryPayment', auctionID, buyer);
+		console.log('*** Result: Payment: ' + prettyJSONString(result.toString()));
+
+		gateway.disconnect();
+	} catch (error) {
+		console.error(`******** FAILED to pay auction: ${error}`);
+	}
+}
+
+async function main () {
+	try {
+		if (process.argv[2] === undefined || process.argv[3] === undefined ||
+            process.argv[4] === undefined) {
+			console.log('Usage: node payAuction.js org userID auctionID [forfeit buyerID]');
+			process.exit(1);
+		}
+
+		const org = process.argv[2];
+		const user = process.argv[3];
+		const auctionID = process.argv[4];
+		const buyer = process.argv[5] === 'forfeit' ? process.argv[6] : undefined;
+
+		if (org === 'Org1' || org === 'org1') {
+			const ccp = buildCCPOrg1();
+			const walletPath = path.join(__dirname, 'wallet/org1');
+			const wallet = await buildWallet(Wallets, walletPath);
+			await payAuction(ccp, wallet, user, auctionID, buyer);
+		} else if (org === 'Org2' || org === 'org2') {
+			const ccp = buildCCPOrg2();
+			const walletPath = path.join(__dirname, 'wallet/org2');
+			const wallet = await buildWallet(Wallets, walletPath);
+			await payAuction(ccp, wallet, user, auctionID, buyer);
+		} else {
+			console.log('Usage: node payAuction.js org userID auctionID [forfeit buyerID]');
+			console.log('Org must be Org1 or Org2');
+		}
+	} catch (error) {
+		console.error(`******** FAILED to run the application: ${error}`);
+	}
+}
+
+main();
@@ -33,7 +33,7 @@ async function addBid (ccp, wallet, user, auctionID, bidID) {
 		// console.log('*** Result:  Bid: ' + prettyJSONString(auctionString.toString()));
 		const auctionJSON = JSON.parse(auctionString);
 
-		const bidData = { objectType: 'bid', quantity: parseInt(bidJSON.quantity), price: parseInt(bidJSON.price), org: bidJSON.org, buyer: bidJSON.buyer };
+		const bidData = { objectType: 'bid', quantity: parseInt(bidJSON.quantity), price: parseInt(bidJSON.price), org: bidJSON.org, buyer: bidJSON.buyer, salt: bidJSON.salt };
 		console.log('*** Result:  Bid: ' + JSON.stringify(bidData, null, 2));
 
 		const statefulTxn = contract.createTransaction('RevealBid');
@@ -30,11 +30,15 @@ async function submitBid (ccp, wallet, user, auctionID, bidID) {
 
 		const statefulTxn = contract.createTransaction('SubmitBid');
 
-		if (auctionJSON.organizations.length === 2) {
-			statefulTxn.setEndorsingOrganizations(auctionJSON.organizations[0], auctionJSON.organizations[1]);
-		} else {
-			statefulTxn.setEndorsingOrganizations(auctionJSON.organizations[0]);
+		// the deposit is escrowed from the balance of the bidder, which is endorsed by the organization of the bidder
+		const orgs = auctionJSON.organizations.slice();
+		if (auctionJSON.deposit > 0) {
+			const bidderMSP = (await wallet.get(user)).mspId;
+			if (!orgs.includes(bidderMSP)) {
+				orgs.push(bidderMSP);
+			}
 		}
+		statefulTxn.setEndorsingOrganizations(...orgs);
 
 		console.log('\n--> Submit Transaction: add bid to the auction');
 		await statefulTxn.submit(auctionID, bidID);
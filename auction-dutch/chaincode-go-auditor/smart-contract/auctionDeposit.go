@@ -0,0 +1,251 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/balance"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
+)
+
+// Payment records the amount that a winner of an auction with a deposit owes to the seller.
+// The deposits of the winning bids of the buyer are applied to the amount, and the status of
+// the payment is pending until the buyer pays the remainder or forfeits the deposits. The
+// buyers of a clock auction pay when they accept the price, so their payments are paid
+type Payment struct {
+	Type       string    `json:"objectType"`
+	AuctionID  string    `json:"auctionID"`
+	Payer      string    `json:"payer"`
+	Payee      string    `json:"payee"`
+	Quantity   int       `json:"quantity"`
+	Amount     int       `json:"amount"`
+	Deposit    int       `json:"deposit"`
+	Status     string    `json:"status"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+const paymentKeyType = "payment"
+
+// PayAuction is used by a winner of an auction with a deposit to pay for the items they won
+// before the payment deadline. The deposits of the winner are applied to the amount, and the
+// rest is taken from the balance of the winner. The amount is added to the balance of the seller
+func (s *SmartContract) PayAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	payment, err := s.QueryPayment(ctx, auctionID, clientID)
+	if err != nil {
+		return err
+	}
+	if payment.Status != "pending" {
+		return fmt.Errorf("payment of auction %v is not pending", auctionID)
+	}
+
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if !txTime.Before(auction.PaymentDeadline) {
+		return fmt.Errorf("cannot pay auction, payment deadline %s has passed", auction.PaymentDeadline.Format(time.RFC3339))
+	}
+
+	changes := make(map[string]int)
+	changes[payment.Payer] -= payment.Amount - payment.Deposit
+	changes[payment.Payee] += payment.Amount
+
+	err = balance.Apply(ctx.GetStub(), changes)
+	if err != nil {
+		return err
+	}
+
+	payment.Status = "paid"
+
+	return putPayment(ctx, payment)
+}
+
+// ForfeitDeposit can be used by anyone after the payment deadline of an auction, when a
+// winner did not pay for the items they won. The deposits of the winner are added to the
+// balance of the seller
+func (s *SmartContract) ForfeitDeposit(ctx contractapi.TransactionContextInterface, auctionID string, buyer string) error {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	payment, err := s.QueryPayment(ctx, auctionID, buyer)
+	if err != nil {
+		return err
+	}
+	if payment.Status != "pending" {
+		return fmt.Errorf("payment of auction %v is not pending", auctionID)
+	}
+
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if txTime.Before(auction.PaymentDeadline) {
+		return fmt.Errorf("cannot forfeit deposit before the payment deadline %s", auction.PaymentDeadline.Format(time.RFC3339))
+	}
+
+	err = balance.Apply(ctx.GetStub(), map[string]int{payment.Payee: payment.Deposit})
+	if err != nil {
+		return err
+	}
+
+	payment.Status = "forfeited"
+
+	return putPayment(ctx, payment)
+}
+
+// QueryPayment allows all members of the channel to read the payment of a winner of an auction
+func (s *SmartContract) QueryPayment(ctx contractapi.TransactionContextInterface, auctionID string, buyer string) (*Payment, error) {
+
+	payment, err := readPayment(ctx, auctionID, buyer)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, fmt.Errorf("no payment has been recorded for buyer %v in auction %v", buyer, auctionID)
+	}
+
+	return payment, nil
+}
+
+// readPayment is an internal function that reads the payment of a buyer from public state.
+// It returns nil if no payment has been recorded for the buyer
+func readPayment(ctx contractapi.TransactionContextInterface, auctionID string, buyer string) (*Payment, error) {
+
+	paymentKey, err := ctx.GetStub().CreateCompositeKey(paymentKeyType, []string{auctionID, identity.Normalize(buyer)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	paymentJSON, err := ctx.GetStub().GetState(paymentKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment %v: %v", paymentKey, err)
+	}
+	if paymentJSON == nil {
+		return nil, nil
+	}
+
+	var payment *Payment
+	err = json.Unmarshal(paymentJSON, &payment)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// settleDeposits is an internal function that settles an auction with a deposit when the
+// auction ends. Each winner keeps one deposit for each of their winning bids, which is
+// applied to their payment, and the deposits of the other revealed bids are refunded. The
+// deposits of the bids that were not revealed are forfeited to the seller. If the deposits
+// of a winner cover the amount, the payment is paid right away
+func settleDeposits(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction) error {
+
+	// count the winning bids and the quantity won by each buyer. The buyers are kept
+	// in the order of the winners, so that the payments are recorded in the same order
+	var buyers []string
+	winningBids := make(map[string]int)
+	quantities := make(map[string]int)
+	if auction.Status == "ended" {
+		for _, winner := range auction.Winners {
+			buyer := identity.Normalize(winner.Buyer)
+			if _, ok := winningBids[buyer]; !ok {
+				buyers = append(buyers, winner.Buyer)
+			}
+			winningBids[buyer]++
+			quantities[buyer] += winner.Quantity
+		}
+	}
+
+	changes := make(map[string]int)
+	keptDeposits := make(map[string]int)
+	for bidKey, privateBid := range auction.PrivateBids {
+		if privateBid.Depositor == "" {
+			continue
+		}
+		if _, revealed := auction.RevealedBids[bidKey]; !revealed {
+			changes[auction.Seller] += auction.Deposit
+			continue
+		}
+		depositor := identity.Normalize(privateBid.Depositor)
+		if keptDeposits[depositor] < winningBids[depositor] {
+			keptDeposits[depositor]++
+			continue
+		}
+		changes[privateBid.Depositor] += auction.Deposit
+	}
+
+	recordedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, buyer := range buyers {
+		quantity := quantities[identity.Normalize(buyer)]
+
+		payment := Payment{
+			Type:       paymentKeyType,
+			AuctionID:  auctionID,
+			Payer:      buyer,
+			Payee:      auction.Seller,
+			Quantity:   quantity,
+			Amount:     auction.Price * quantity,
+			Deposit:    keptDeposits[identity.Normalize(buyer)] * auction.Deposit,
+			Status:     "pending",
+			RecordedAt: recordedAt,
+		}
+
+		if payment.Amount <= payment.Deposit {
+			changes[buyer] += payment.Deposit - payment.Amount
+			changes[auction.Seller] += payment.Amount
+			payment.Status = "paid"
+		}
+
+		err = putPayment(ctx, &payment)
+		if err != nil {
+			return err
+		}
+	}
+
+	return balance.Apply(ctx.GetStub(), changes)
+}
+
+// putPayment is an internal function that puts the payment of a winner into public state
+func putPayment(ctx contractapi.TransactionContextInterface, payment *Payment) error {
+
+	paymentJSON, err := json.Marshal(payment)
+	if err != nil {
+		return err
+	}
+
+	paymentKey, err := ctx.GetStub().CreateCompositeKey(paymentKeyType, []string{payment.AuctionID, identity.Normalize(payment.Payer)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(paymentKey, paymentJSON)
+	if err != nil {
+		return fmt.Errorf("failed to record payment: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/balance"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
+)
+
+// issuerMSP is the organization whose admins issue units. This sample assumes Org1 is the issuer
+const issuerMSP = "Org1MSP"
+
+// Mint can be used by an admin of the issuer organization to issue new units to an account.
+// Accounts are identified by the client ID returned by GetSubmittingClientIdentity. The
+// organization of the account needs to endorse the transaction along with the issuer
+func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, account string, amount int) error {
+
+	if amount <= 0 {
+		return fmt.Errorf("amount must be a positive integer")
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+	if clientOrgID != issuerMSP {
+		return fmt.Errorf("submitting client is not a member of the issuer organization %s", issuerMSP)
+	}
+
+	err = identity.AssertAdmin(ctx.GetClientIdentity())
+	if err != nil {
+		return err
+	}
+
+	return balance.Apply(ctx.GetStub(), map[string]int{account: amount})
+}
+
+// Transfer moves units from the balance of the submitting client to another account.
+// The organizations of both accounts need to endorse the transaction
+func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, recipient string, amount int) error {
+
+	if amount <= 0 {
+		return fmt.Errorf("amount must be a positive integer")
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+	if identity.Same(clientID, recipient) {
+		return fmt.Errorf("cannot transfer units to the submitting client")
+	}
+
+	return balance.Apply(ctx.GetStub(), map[string]int{clientID: -amount, recipient: amount})
+}
+
+// QueryBalance allows all members of the channel to read the balance of an account.
+// Accounts that never held any units have a balance of 0
+func (s *SmartContract) QueryBalance(ctx contractapi.TransactionContextInterface, account string) (int, error) {
+
+	return balance.Read(ctx.GetStub(), account)
+}
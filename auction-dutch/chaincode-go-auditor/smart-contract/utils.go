@@ -5,27 +5,21 @@ SPDX-License-Identifier: Apache-2.0
 package auction
 
 import (
-	"encoding/base64"
 	"fmt"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
 )
 
+// GetSubmittingClientIdentity returns the MSP ID, name and issuer of the identity
+// that invokes the smart contract, in the <mspID>::x509::<subject>::<issuer> format.
 func (s *SmartContract) GetSubmittingClientIdentity(ctx contractapi.TransactionContextInterface) (string, error) {
-
-	b64ID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return "", fmt.Errorf("Failed to read clientID: %v", err)
-	}
-	decodeID, err := base64.StdEncoding.DecodeString(b64ID)
-	if err != nil {
-		return "", fmt.Errorf("failed to base64 decode clientID: %v", err)
-	}
-	return string(decodeID), nil
+	return identity.ID(ctx.GetClientIdentity())
 }
 
 // getCollectionName is an internal helper function to get collection of submitting client identity.
@@ -201,3 +195,25 @@ func setAssetStateBasedEndorsement(ctx contractapi.TransactionContextInterface,
 	}
 	return nil
 }
+
+// getTxTimestamp returns the timestamp of the transaction, which is set by the client
+// and is the same on all endorsing peers
+func getTxTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	return time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC(), nil
+}
+
+// closeAfterBiddingDeadline is an internal function that closes an open auction once the
+// bidding deadline has passed, so that bids can be revealed without waiting for the auction
+// to be closed
+func closeAfterBiddingDeadline(auction *Auction, txTime time.Time) {
+
+	if auction.Status == "open" && !txTime.Before(auction.BiddingDeadline) {
+		auction.Status = string("closed")
+	}
+}
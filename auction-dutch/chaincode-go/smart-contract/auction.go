@@ -10,8 +10,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/balance"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
 )
 
 type SmartContract struct {
@@ -20,17 +23,22 @@ type SmartContract struct {
 
 // Auction data
 type Auction struct {
-	Type         string             `json:"objectType"`
-	ItemSold     string             `json:"item"`
-	Seller       string             `json:"seller"`
-	Quantity     int                `json:"quantity"`
-	Orgs         []string           `json:"organizations"`
-	PrivateBids  map[string]BidHash `json:"privateBids"`
-	RevealedBids map[string]FullBid `json:"revealedBids"`
-	Winners      []Winners          `json:"winners"`
-	Price        int                `json:"price"`
-	Status       string             `json:"status"`
-	Auditor      bool               `json:"auditor"`
+	Type            string             `json:"objectType"`
+	ItemSold        string             `json:"item"`
+	Seller          string             `json:"seller"`
+	Quantity        int                `json:"quantity"`
+	Orgs            []string           `json:"organizations"`
+	PrivateBids     map[string]BidHash `json:"privateBids"`
+	RevealedBids    map[string]FullBid `json:"revealedBids"`
+	Winners         []Winners          `json:"winners"`
+	Price           int                `json:"price"`
+	Status          string             `json:"status"`
+	Auditor         bool               `json:"auditor"`
+	BiddingDeadline time.Time          `json:"biddingDeadline"`
+	RevealDeadline  time.Time          `json:"revealDeadline"`
+	Deposit         int                `json:"deposit"`
+	PaymentDeadline time.Time          `json:"paymentDeadline"`
+	Clock           *Clock             `json:"clock,omitempty"`
 }
 
 // FullBid is the structure of a revealed bid
@@ -40,25 +48,56 @@ type FullBid struct {
 	Price    int    `json:"price"`
 	Org      string `json:"org"`
 	Buyer    string `json:"buyer"`
+	Salt     string `json:"salt,omitempty"`
 }
 
 // BidHash is the structure of a private bid
 type BidHash struct {
-	Org  string `json:"org"`
-	Hash string `json:"hash"`
+	Org       string `json:"org"`
+	Hash      string `json:"hash"`
+	Depositor string `json:"depositor,omitempty"`
 }
 
-// Winners stores the winners of the auction
+// Winners stores the winners of the auction. In a clock auction, each winner
+// pays the price of the clock when they accepted it
 type Winners struct {
 	Buyer    string `json:"buyer"`
 	Quantity int    `json:"quantity"`
+	Price    int    `json:"price,omitempty"`
 }
 
 const bidKeyType = "bid"
 
+// minSaltLength is the minimum length of the random salt that is added to each bid,
+// so that the price of a bid cannot be guessed from the hash that is added to the auction
+const minSaltLength = 16
+
 // CreateAuction creates on auction on the public channel. The identity that
-// submits the transacion becomes the seller of the auction
-func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterface, auctionID string, itemsold string, quantity int, withAuditor string) error {
+// submits the transacion becomes the seller of the auction. Bids can be submitted
+// until the bidding deadline, and revealed until the reveal deadline. Both deadlines
+// are RFC3339 timestamps that are compared with the timestamp of the transactions.
+// If the deposit is greater than 0, each bid needs to escrow the deposit from the balance of
+// the bidder when it is submitted. The deposits of the winners are applied to their payment,
+// and the winners need to pay the rest before the payment deadline or forfeit their deposit
+func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterface, auctionID string, itemsold string, quantity int, withAuditor string, deposit int, biddingDeadline string, revealDeadline string, paymentDeadline string) error {
+
+	if deposit < 0 {
+		return fmt.Errorf("deposit cannot be negative")
+	}
+
+	biddingDeadlineTime, revealDeadlineTime, err := parseDeadlines(ctx, biddingDeadline, revealDeadline)
+	if err != nil {
+		return err
+	}
+
+	// the payment deadline is only used by auctions with a deposit
+	var paymentDeadlineTime time.Time
+	if deposit > 0 {
+		paymentDeadlineTime, err = parsePaymentDeadline(paymentDeadline, revealDeadlineTime)
+		if err != nil {
+			return err
+		}
+	}
 
 	// get ID of submitting client
 	clientID, err := s.GetSubmittingClientIdentity(ctx)
@@ -83,17 +122,21 @@ func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterfac
 	revealedBids := make(map[string]FullBid)
 
 	auction := Auction{
-		Type:         "auction",
-		ItemSold:     itemsold,
-		Quantity:     quantity,
-		Price:        0,
-		Seller:       clientID,
-		Orgs:         []string{clientOrgID},
-		PrivateBids:  bidders,
-		RevealedBids: revealedBids,
-		Winners:      []Winners{},
-		Status:       "open",
-		Auditor:      auditor,
+		Type:            "auction",
+		ItemSold:        itemsold,
+		Quantity:        quantity,
+		Price:           0,
+		Seller:          clientID,
+		Orgs:            []string{clientOrgID},
+		PrivateBids:     bidders,
+		RevealedBids:    revealedBids,
+		Winners:         []Winners{},
+		Status:          "open",
+		Auditor:         auditor,
+		BiddingDeadline: biddingDeadlineTime,
+		RevealDeadline:  revealDeadlineTime,
+		Deposit:         deposit,
+		PaymentDeadline: paymentDeadlineTime,
 	}
 
 	auctionJSON, err := json.Marshal(auction)
@@ -132,6 +175,12 @@ func (s *SmartContract) Bid(ctx contractapi.TransactionContextInterface, auction
 		return "", fmt.Errorf("bid key not found in the transient map")
 	}
 
+	// the bid needs to include a random salt, and is stored in a canonical form
+	bidJSON, err = marshalBid(bidJSON)
+	if err != nil {
+		return "", err
+	}
+
 	// get the implicit collection name using the bidder's organization ID
 	collection, err := getCollectionName(ctx)
 	if err != nil {
@@ -165,68 +214,52 @@ func (s *SmartContract) Bid(ctx contractapi.TransactionContextInterface, auction
 
 // SubmitBid is used by the bidder to add the hash of that bid stored in private data to the
 // auction. Note that this function alters the auction in private state, and needs
-// to meet the auction endorsement policy. Transaction ID is used identify the bid
+// to meet the auction endorsement policy. Transaction ID is used identify the bid.
+// If the auction requires a deposit, the deposit is taken from the balance of the submitting client
 func (s *SmartContract) SubmitBid(ctx contractapi.TransactionContextInterface, auctionID string, txID string) error {
 
-	// get the MSP ID of the bidder's org
-	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return fmt.Errorf("failed to get client MSP ID: %v", err)
-	}
-
 	// get the auction from public state
 	auction, err := s.QueryAuction(ctx, auctionID)
 	if err != nil {
 		return fmt.Errorf("failed to get auction from public state %v", err)
 	}
 
+	// units of a clock auction are bought using AcceptPrice
+	err = checkSealedBid(auction)
+	if err != nil {
+		return fmt.Errorf("cannot join auction: %v", err)
+	}
+
 	// the auction needs to be open for users to add their bid
 	status := auction.Status
 	if status != "open" {
 		return fmt.Errorf("cannot join closed or ended auction")
 	}
 
-	// get the inplicit collection name of bidder's org
-	collection, err := getCollectionName(ctx)
+	// bids cannot be added after the bidding deadline, even if the auction was not closed
+	txTime, err := getTxTimestamp(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get implicit collection name: %v", err)
+		return err
 	}
-
-	// use the transaction ID passed as a parameter to create composite bid key
-	bidKey, err := ctx.GetStub().CreateCompositeKey(bidKeyType, []string{auctionID, txID})
-	if err != nil {
-		return fmt.Errorf("failed to create composite key: %v", err)
+	if !txTime.Before(auction.BiddingDeadline) {
+		return fmt.Errorf("cannot join auction, bidding deadline %s has passed", auction.BiddingDeadline.Format(time.RFC3339))
 	}
 
-	// get the hash of the bid if found in private collection
-	bidHash, err := ctx.GetStub().GetPrivateDataHash(collection, bidKey)
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to read bid bash from collection: %v", err)
-	}
-	if bidHash == nil {
-		return fmt.Errorf("bid hash does not exist: %s", bidKey)
+		return fmt.Errorf("failed to get client identity %v", err)
 	}
 
-	// store the hash along with the bidder's organization
-	newHash := BidHash{
-		Org:  clientOrgID,
-		Hash: fmt.Sprintf("%x", bidHash),
+	err = addPrivateBid(ctx, auctionID, auction, txID, clientID)
+	if err != nil {
+		return err
 	}
 
-	bidders := make(map[string]BidHash)
-	bidders = auction.PrivateBids
-	bidders[bidKey] = newHash
-	auction.PrivateBids = bidders
-
-	// Add the bidding organization to the list of participating organization's if it is not already
-	orgs := auction.Orgs
-	if !(contains(orgs, clientOrgID)) {
-		newOrgs := append(orgs, clientOrgID)
-		auction.Orgs = newOrgs
-
-		err = setAssetStateBasedEndorsement(ctx, auctionID, newOrgs, auction.Auditor)
+	// escrow the deposit until the auction ends or the bid is withdrawn
+	if auction.Deposit > 0 {
+		err = balance.Apply(ctx.GetStub(), map[string]int{clientID: -auction.Deposit})
 		if err != nil {
-			return fmt.Errorf("failed setting state based endorsement for new organization: %v", err)
+			return fmt.Errorf("failed to escrow deposit: %v", err)
 		}
 	}
 
@@ -254,6 +287,13 @@ func (s *SmartContract) RevealBid(ctx contractapi.TransactionContextInterface, a
 		return fmt.Errorf("bid key not found in the transient map")
 	}
 
+	// the revealed bid needs to include the salt, and is hashed in the
+	// same canonical form as the bid that was stored
+	bidJSON, err := marshalBid(transientBidJSON)
+	if err != nil {
+		return err
+	}
+
 	// get implicit collection name of organization ID
 	collection, err := getCollectionName(ctx)
 	if err != nil {
@@ -281,21 +321,36 @@ func (s *SmartContract) RevealBid(ctx contractapi.TransactionContextInterface, a
 		return fmt.Errorf("failed to get auction from public state %v", err)
 	}
 
+	err = checkSealedBid(auction)
+	if err != nil {
+		return fmt.Errorf("cannot reveal bid: %v", err)
+	}
+
 	// Complete a series of three checks before we add the bid to the auction
 
 	// check 1: check that the auction is closed. We cannot reveal an
-	// bid to an open auction
+	// bid to an open auction. The auction is closed once the bidding deadline
+	// has passed, and bids cannot be revealed after the reveal deadline
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	closeAfterBiddingDeadline(auction, txTime)
+
 	status := auction.Status
 	if status != "closed" {
 		return fmt.Errorf("cannot reveal bid for open or ended auction")
 	}
+	if !txTime.Before(auction.RevealDeadline) {
+		return fmt.Errorf("cannot reveal bid, reveal deadline %s has passed", auction.RevealDeadline.Format(time.RFC3339))
+	}
 
 	// check 2: check that hash of revealed bid matches hash of private bid
 	// on the public ledger. This checks that the bidder is telling the truth
 	// about the value of their bid
 
 	hash := sha256.New()
-	hash.Write(transientBidJSON)
+	hash.Write(bidJSON)
 	calculatedBidJSONHash := hash.Sum(nil)
 
 	// verify that the hash of the passed immutable properties matches the on-chain hash
@@ -354,7 +409,7 @@ func (s *SmartContract) RevealBid(ctx contractapi.TransactionContextInterface, a
 	}
 
 	// check 4: make sure that the transaction is being submitted is the bidder
-	if bidInput.Buyer != clientID {
+	if !identity.Same(bidInput.Buyer, clientID) {
 		return fmt.Errorf("Permission denied, client id %v is not the owner of the bid", clientID)
 	}
 
@@ -374,8 +429,9 @@ func (s *SmartContract) RevealBid(ctx contractapi.TransactionContextInterface, a
 	return nil
 }
 
-// CloseAuction can be used by the seller to close the auction. This prevents
-// bids from being added to the auction, and allows users to reveal their bid
+// CloseAuction can be used by anyone to close the auction after the bidding deadline.
+// This prevents bids from being added to the auction, and allows users to reveal their bid.
+// The auction cannot be closed before the deadline, so that the seller cannot shut out bidders
 func (s *SmartContract) CloseAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
 
 	// get auction from public state
@@ -384,17 +440,9 @@ func (s *SmartContract) CloseAuction(ctx contractapi.TransactionContextInterface
 		return fmt.Errorf("failed to get auction from public state %v", err)
 	}
 
-	// the auction can only be closed by the seller
-
-	// get ID of submitting client
-	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	err = checkSealedBid(auction)
 	if err != nil {
-		return fmt.Errorf("failed to get client identity %v", err)
-	}
-
-	seller := auction.Seller
-	if seller != clientID {
-		return fmt.Errorf("auction can only be closed by seller: %v", err)
+		return fmt.Errorf("cannot close auction: %v", err)
 	}
 
 	status := auction.Status
@@ -402,6 +450,14 @@ func (s *SmartContract) CloseAuction(ctx contractapi.TransactionContextInterface
 		return fmt.Errorf("cannot close auction that is not open")
 	}
 
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if txTime.Before(auction.BiddingDeadline) {
+		return fmt.Errorf("cannot close auction before the bidding deadline %s", auction.BiddingDeadline.Format(time.RFC3339))
+	}
+
 	auction.Status = string("closed")
 
 	closedAuctionJSON, _ := json.Marshal(auction)
@@ -415,7 +471,9 @@ func (s *SmartContract) CloseAuction(ctx contractapi.TransactionContextInterface
 }
 
 // EndAuction both changes the auction status to closed and calculates the winners
-// of the auction
+// of the auction. The seller can end the auction after the bidding deadline, and
+// anyone can end the auction after the reveal deadline. Bids that were not revealed
+// before the reveal deadline are forfeited
 func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
 
 	// get auction from public state
@@ -424,17 +482,28 @@ func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("failed to get auction from public state %v", err)
 	}
 
-	// Check that the auction is being ended by the seller
-
 	// get ID of submitting client
 	clientID, err := s.GetSubmittingClientIdentity(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get client identity %v", err)
 	}
 
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	// clock auctions have no bids to reveal, and are ended separately
+	if auction.Clock != nil {
+		return endClockAuction(ctx, auctionID, auction, clientID, txTime)
+	}
+
+	closeAfterBiddingDeadline(auction, txTime)
+	revealDeadlinePassed := !txTime.Before(auction.RevealDeadline)
+
+	// Check that the auction is being ended by the seller, unless the reveal deadline has passed
 	seller := auction.Seller
-	if seller != clientID {
-		return fmt.Errorf("auction can only be ended by seller: %v", err)
+	if !identity.Same(seller, clientID) && !revealDeadlinePassed {
+		return fmt.Errorf("auction can only be ended by seller before the reveal deadline %s", auction.RevealDeadline.Format(time.RFC3339))
 	}
 
 	status := auction.Status
@@ -446,7 +515,11 @@ func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface,
 
 	revealedBidMap := auction.RevealedBids
 	if len(auction.RevealedBids) == 0 {
-		return fmt.Errorf("No bids have been revealed, cannot end auction: %v", err)
+		if !revealDeadlinePassed {
+			return fmt.Errorf("No bids have been revealed, cannot end auction before the reveal deadline")
+		}
+		auction.Status = string("no sale")
+		return putEndedAuction(ctx, auctionID, auction)
 	}
 
 	// sort the map of revealed bids to make it easier to calculate winners
@@ -499,17 +572,34 @@ func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface,
 		}
 	}
 
-	// check if there is a winning bid that has yet to be revealed
-	err = checkForHigherBid(ctx, auction.Price, auction.RevealedBids, auction.PrivateBids)
-	if err != nil {
-		return fmt.Errorf("Cannot end auction: %v", err)
+	// check if there is a winning bid that has yet to be revealed. Bids that were
+	// not revealed before the reveal deadline are forfeited and cannot win the auction
+	if !revealDeadlinePassed {
+		err = checkForHigherBid(ctx, auction.Price, auction.RevealedBids, auction.PrivateBids)
+		if err != nil {
+			return fmt.Errorf("Cannot end auction: %v", err)
+		}
 	}
 
 	auction.Status = string("ended")
 
+	return putEndedAuction(ctx, auctionID, auction)
+}
+
+// putEndedAuction is an internal function that puts an auction that has ended back into state.
+// The deposits of auctions with a deposit are settled when the auction ends
+func putEndedAuction(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction) error {
+
+	if auction.Deposit > 0 {
+		err := settleDeposits(ctx, auctionID, auction)
+		if err != nil {
+			return err
+		}
+	}
+
 	endedAuctionJSON, _ := json.Marshal(auction)
 
-	err = ctx.GetStub().PutState(auctionID, endedAuctionJSON)
+	err := ctx.GetStub().PutState(auctionID, endedAuctionJSON)
 	if err != nil {
 		return fmt.Errorf("failed to end auction: %v", err)
 	}
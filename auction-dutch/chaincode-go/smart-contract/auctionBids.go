@@ -0,0 +1,273 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/balance"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
+)
+
+// WithdrawBid is used by a bidder to remove their bid from the auction before the
+// auction is closed. The bidder proves that they own the bid by passing the bid
+// using the "bid" key of the transient map, in the same way as when revealing the bid.
+// The bid is also deleted from the implicit collection of the bidder's organization.
+// If the auction requires a deposit, the deposit of the bid is refunded
+func (s *SmartContract) WithdrawBid(ctx contractapi.TransactionContextInterface, auctionID string, txID string) error {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	err = checkBiddingOpen(ctx, auction)
+	if err != nil {
+		return fmt.Errorf("cannot withdraw bid: %v", err)
+	}
+
+	removed, err := s.removePrivateBid(ctx, auctionID, auction, txID)
+	if err != nil {
+		return err
+	}
+
+	if removed.Depositor != "" {
+		err = balance.Apply(ctx.GetStub(), map[string]int{removed.Depositor: auction.Deposit})
+		if err != nil {
+			return err
+		}
+	}
+
+	newAuctionJSON, _ := json.Marshal(auction)
+
+	err = ctx.GetStub().PutState(auctionID, newAuctionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return nil
+}
+
+// ReplaceBid is used by a bidder to replace their bid with a new bid before the auction
+// is closed. The new bid needs to be created using the Bid function first. The bidder
+// passes the bid that is replaced using the "bid" key of the transient map. The deposit
+// of the replaced bid is kept for the new bid
+func (s *SmartContract) ReplaceBid(ctx contractapi.TransactionContextInterface, auctionID string, txID string, newTxID string) error {
+
+	if txID == newTxID {
+		return fmt.Errorf("cannot replace bid %v with itself", txID)
+	}
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	err = checkBiddingOpen(ctx, auction)
+	if err != nil {
+		return fmt.Errorf("cannot replace bid: %v", err)
+	}
+
+	removed, err := s.removePrivateBid(ctx, auctionID, auction, txID)
+	if err != nil {
+		return err
+	}
+
+	err = addPrivateBid(ctx, auctionID, auction, newTxID, removed.Depositor)
+	if err != nil {
+		return err
+	}
+
+	newAuctionJSON, _ := json.Marshal(auction)
+
+	err = ctx.GetStub().PutState(auctionID, newAuctionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return nil
+}
+
+// checkBiddingOpen is an internal function that checks that bids can still be added to
+// or removed from the auction
+func checkBiddingOpen(ctx contractapi.TransactionContextInterface, auction *Auction) error {
+
+	err := checkSealedBid(auction)
+	if err != nil {
+		return err
+	}
+
+	if auction.Status != "open" {
+		return fmt.Errorf("auction is closed or ended")
+	}
+
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if !txTime.Before(auction.BiddingDeadline) {
+		return fmt.Errorf("bidding deadline %s has passed", auction.BiddingDeadline.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// addPrivateBid is an internal function that adds the hash of a bid stored in the implicit
+// collection of the bidder's organization to the auction. The organization of the bidder
+// is added as an endorser of the auction if it is not already. The depositor is recorded
+// with the bid if the auction requires a deposit
+func addPrivateBid(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction, txID string, depositor string) error {
+
+	// get the MSP ID of the bidder's org
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	// get the inplicit collection name of bidder's org
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	// use the transaction ID passed as a parameter to create composite bid key
+	bidKey, err := ctx.GetStub().CreateCompositeKey(bidKeyType, []string{auctionID, txID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	// get the hash of the bid if found in private collection
+	bidHash, err := ctx.GetStub().GetPrivateDataHash(collection, bidKey)
+	if err != nil {
+		return fmt.Errorf("failed to read bid bash from collection: %v", err)
+	}
+	if bidHash == nil {
+		return fmt.Errorf("bid hash does not exist: %s", bidKey)
+	}
+
+	// store the hash along with the bidder's organization
+	newHash := BidHash{
+		Org:  clientOrgID,
+		Hash: fmt.Sprintf("%x", bidHash),
+	}
+	if auction.Deposit > 0 {
+		newHash.Depositor = depositor
+	}
+
+	bidders := make(map[string]BidHash)
+	bidders = auction.PrivateBids
+	bidders[bidKey] = newHash
+	auction.PrivateBids = bidders
+
+	// Add the bidding organization to the list of participating organization's if it is not already
+	orgs := auction.Orgs
+	if !(contains(orgs, clientOrgID)) {
+		newOrgs := append(orgs, clientOrgID)
+		auction.Orgs = newOrgs
+
+		err = setAssetStateBasedEndorsement(ctx, auctionID, newOrgs, auction.Auditor)
+		if err != nil {
+			return fmt.Errorf("failed setting state based endorsement for new organization: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// removePrivateBid is an internal function that removes a bid from the auction, after
+// checking that the bid passed in the transient map is the bid that was added to the
+// auction and that it was created by the submitting client. The removed bid hash is returned
+func (s *SmartContract) removePrivateBid(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction, txID string) (BidHash, error) {
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return BidHash{}, fmt.Errorf("error getting transient: %v", err)
+	}
+
+	transientBidJSON, ok := transientMap["bid"]
+	if !ok {
+		return BidHash{}, fmt.Errorf("bid key not found in the transient map")
+	}
+
+	// the bid is stored in the implicit collection of the bidder's organization
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return BidHash{}, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	bidKey, err := ctx.GetStub().CreateCompositeKey(bidKeyType, []string{auctionID, txID})
+	if err != nil {
+		return BidHash{}, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	privateBid, ok := auction.PrivateBids[bidKey]
+	if !ok {
+		return BidHash{}, fmt.Errorf("bid %v has not been added to the auction", txID)
+	}
+
+	// check that the bid passed in the transient map is the bid that was added to the auction
+	bidJSON, err := marshalBid(transientBidJSON)
+	if err != nil {
+		return BidHash{}, err
+	}
+	calculatedBidJSONHash := sha256.Sum256(bidJSON)
+
+	bidHash, err := ctx.GetStub().GetPrivateDataHash(collection, bidKey)
+	if err != nil {
+		return BidHash{}, fmt.Errorf("failed to read bid bash from collection: %v", err)
+	}
+	if !bytes.Equal(calculatedBidJSONHash[:], bidHash) || privateBid.Hash != fmt.Sprintf("%x", bidHash) {
+		return BidHash{}, fmt.Errorf("hash %x for bid JSON %s does not match hash in auction: %s",
+			calculatedBidJSONHash,
+			transientBidJSON,
+			privateBid.Hash,
+		)
+	}
+
+	var bid FullBid
+	err = json.Unmarshal(transientBidJSON, &bid)
+	if err != nil {
+		return BidHash{}, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return BidHash{}, fmt.Errorf("failed to get client identity %v", err)
+	}
+	if !identity.Same(bid.Buyer, clientID) {
+		return BidHash{}, fmt.Errorf("Permission denied, client id %v is not the owner of the bid", clientID)
+	}
+
+	delete(auction.PrivateBids, bidKey)
+
+	err = ctx.GetStub().DelPrivateData(collection, bidKey)
+	if err != nil {
+		return BidHash{}, fmt.Errorf("failed to delete bid from collection: %v", err)
+	}
+
+	return privateBid, nil
+}
+
+// marshalBid validates a bid passed in the transient map and marshals it in a canonical form,
+// so that the hash does not depend on the formatting of the JSON passed by the bidder. Bids
+// without a random salt are rejected, as their price could be guessed from their hash
+func marshalBid(transientBidJSON []byte) ([]byte, error) {
+
+	var bid FullBid
+	err := json.Unmarshal(transientBidJSON, &bid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+	if len(bid.Salt) < minSaltLength {
+		return nil, fmt.Errorf("bid requires a random salt of at least %d characters", minSaltLength)
+	}
+
+	return json.Marshal(bid)
+}
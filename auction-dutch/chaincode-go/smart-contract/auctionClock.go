@@ -0,0 +1,313 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/balance"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
+)
+
+// Clock holds the parameters of a descending clock auction. The price starts at the
+// start price when the auction is created, and drops by the decrement at the end of
+// each step. The step interval is a number of seconds. LastAcceptedAt is the timestamp
+// of the last transaction that accepted the price of the clock
+type Clock struct {
+	StartPrice     int       `json:"startPrice"`
+	Decrement      int       `json:"decrement"`
+	StepInterval   int       `json:"stepInterval"`
+	StartTime      time.Time `json:"startTime"`
+	LastAcceptedAt time.Time `json:"lastAcceptedAt"`
+}
+
+// CreateClockAuction creates a descending clock auction on the public channel. The identity
+// that submits the transaction becomes the seller of the auction. Instead of submitting sealed
+// bids, buyers use AcceptPrice to buy units at the current price of the clock, until the
+// quantity is sold or the deadline has passed. The deadline is an RFC3339 timestamp, and the
+// price of the clock cannot drop to 0 before the deadline
+func (s *SmartContract) CreateClockAuction(ctx contractapi.TransactionContextInterface, auctionID string, itemsold string, quantity int, withAuditor string, startPrice int, decrement int, stepInterval int, deadline string) error {
+
+	if quantity <= 0 {
+		return fmt.Errorf("quantity must be a positive integer")
+	}
+	if startPrice <= 0 || decrement <= 0 || stepInterval <= 0 {
+		return fmt.Errorf("start price, decrement and step interval must be positive integers")
+	}
+
+	deadlineTime, err := time.Parse(time.RFC3339, deadline)
+	if err != nil {
+		return fmt.Errorf("deadline must be an RFC3339 timestamp: %v", err)
+	}
+
+	startTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if !deadlineTime.After(startTime) {
+		return fmt.Errorf("deadline %s is not after the transaction timestamp %s", deadline, startTime.Format(time.RFC3339))
+	}
+
+	clock := Clock{
+		StartPrice:   startPrice,
+		Decrement:    decrement,
+		StepInterval: stepInterval,
+		StartTime:    startTime,
+	}
+	if currentPrice(&clock, deadlineTime) <= 0 {
+		return fmt.Errorf("price of the clock drops to 0 before the deadline %s", deadline)
+	}
+
+	// get ID of submitting client
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	// get org of submitting client
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	auditor := false
+
+	if withAuditor == "withAuditor" {
+		auditor = true
+	}
+
+	// the deadline of the clock is used as both the bidding and the reveal deadline,
+	// as there are no bids to reveal
+	auction := Auction{
+		Type:            "auction",
+		ItemSold:        itemsold,
+		Quantity:        quantity,
+		Price:           0,
+		Seller:          clientID,
+		Orgs:            []string{clientOrgID},
+		PrivateBids:     make(map[string]BidHash),
+		RevealedBids:    make(map[string]FullBid),
+		Winners:         []Winners{},
+		Status:          "open",
+		Auditor:         auditor,
+		BiddingDeadline: deadlineTime.UTC(),
+		RevealDeadline:  deadlineTime.UTC(),
+		Clock:           &clock,
+	}
+
+	auctionJSON, err := json.Marshal(auction)
+	if err != nil {
+		return err
+	}
+
+	// put auction into state
+	err = ctx.GetStub().PutState(auctionID, auctionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put auction in public data: %v", err)
+	}
+
+	// set the seller of the auction as an endorser
+	err = setAssetStateBasedEndorsement(ctx, auctionID, []string{clientOrgID}, auditor)
+	if err != nil {
+		return fmt.Errorf("failed setting state based endorsement for new organization: %v", err)
+	}
+
+	return nil
+}
+
+// AcceptPrice is used by a buyer to buy units of a clock auction at the current price of
+// the clock. The buyer receives the quantity requested, or the remaining quantity if fewer
+// units are left, and pays for the units from their balance right away. The auction ends
+// when all units are sold. The price is calculated from the timestamp of the transaction,
+// which is set by the client. To limit how far a buyer can move the clock forward to lower
+// the price, the timestamp cannot be before the timestamp of the last accepted price
+func (s *SmartContract) AcceptPrice(ctx contractapi.TransactionContextInterface, auctionID string, quantity int) error {
+
+	if quantity <= 0 {
+		return fmt.Errorf("quantity must be a positive integer")
+	}
+
+	// get auction from public state
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+	if auction.Clock == nil {
+		return fmt.Errorf("auction %v is not a clock auction", auctionID)
+	}
+	if auction.Status != "open" {
+		return fmt.Errorf("cannot accept price of an ended auction")
+	}
+
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if !txTime.Before(auction.BiddingDeadline) {
+		return fmt.Errorf("cannot accept price, deadline %s has passed", auction.BiddingDeadline.Format(time.RFC3339))
+	}
+	if txTime.Before(auction.Clock.StartTime) || txTime.Before(auction.Clock.LastAcceptedAt) {
+		return fmt.Errorf("cannot accept price, transaction timestamp %s is before the last accepted price", txTime.Format(time.RFC3339Nano))
+	}
+
+	// get ID of submitting client
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+	if identity.Same(auction.Seller, clientID) {
+		return fmt.Errorf("the seller cannot buy units of their own auction")
+	}
+
+	// get org of submitting client
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	remainingQuantity := auction.Quantity
+	for _, winner := range auction.Winners {
+		remainingQuantity = remainingQuantity - winner.Quantity
+	}
+	if quantity > remainingQuantity {
+		quantity = remainingQuantity
+	}
+
+	price := currentPrice(auction.Clock, txTime)
+
+	auction.Winners = append(auction.Winners, Winners{
+		Buyer:    clientID,
+		Quantity: quantity,
+		Price:    price,
+	})
+	auction.Price = price
+	auction.Clock.LastAcceptedAt = txTime
+
+	err = payClockPrice(ctx, auctionID, auction.Seller, clientID, quantity, price, txTime)
+	if err != nil {
+		return err
+	}
+
+	// Add the buying organization to the list of participating organizations if it is not already
+	orgs := auction.Orgs
+	if !(contains(orgs, clientOrgID)) {
+		newOrgs := append(orgs, clientOrgID)
+		auction.Orgs = newOrgs
+
+		err = setAssetStateBasedEndorsement(ctx, auctionID, newOrgs, auction.Auditor)
+		if err != nil {
+			return fmt.Errorf("failed setting state based endorsement for new organization: %v", err)
+		}
+	}
+
+	if quantity == remainingQuantity {
+		auction.Status = string("ended")
+		return putEndedAuction(ctx, auctionID, auction)
+	}
+
+	auctionJSON, _ := json.Marshal(auction)
+
+	err = ctx.GetStub().PutState(auctionID, auctionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return nil
+}
+
+// QueryCurrentPrice allows all members of the channel to read the price of a clock auction
+// at the timestamp of the transaction
+func (s *SmartContract) QueryCurrentPrice(ctx contractapi.TransactionContextInterface, auctionID string) (int, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+	if auction.Clock == nil {
+		return 0, fmt.Errorf("auction %v is not a clock auction", auctionID)
+	}
+
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return currentPrice(auction.Clock, txTime), nil
+}
+
+// endClockAuction is an internal function that ends a clock auction before all units are sold.
+// The seller can end the auction at any time, and anyone can end the auction after the deadline
+func endClockAuction(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction, clientID string, txTime time.Time) error {
+
+	if auction.Status != "open" {
+		return fmt.Errorf("Can only end an open auction")
+	}
+	if !identity.Same(auction.Seller, clientID) && txTime.Before(auction.BiddingDeadline) {
+		return fmt.Errorf("auction can only be ended by seller before the deadline %s", auction.BiddingDeadline.Format(time.RFC3339))
+	}
+
+	auction.Status = string("ended")
+	if len(auction.Winners) == 0 {
+		auction.Status = string("no sale")
+	}
+
+	return putEndedAuction(ctx, auctionID, auction)
+}
+
+// payClockPrice is an internal function that moves the price of the units bought in a clock
+// auction from the balance of the buyer to the balance of the seller. The units are added to
+// the payment of the buyer, which records all units the buyer bought in the auction
+func payClockPrice(ctx contractapi.TransactionContextInterface, auctionID string, seller string, buyer string, quantity int, price int, txTime time.Time) error {
+
+	amount := quantity * price
+	err := balance.Apply(ctx.GetStub(), map[string]int{buyer: -amount, seller: amount})
+	if err != nil {
+		return err
+	}
+
+	payment, err := readPayment(ctx, auctionID, buyer)
+	if err != nil {
+		return err
+	}
+	if payment == nil {
+		payment = &Payment{
+			Type:      paymentKeyType,
+			AuctionID: auctionID,
+			Payer:     buyer,
+			Payee:     seller,
+			Status:    "paid",
+		}
+	}
+	payment.Quantity += quantity
+	payment.Amount += amount
+	payment.RecordedAt = txTime
+
+	return putPayment(ctx, payment)
+}
+
+// currentPrice is an internal function that calculates the price of the clock at the given time
+func currentPrice(clock *Clock, txTime time.Time) int {
+
+	if !txTime.After(clock.StartTime) {
+		return clock.StartPrice
+	}
+
+	steps := int(txTime.Sub(clock.StartTime) / (time.Duration(clock.StepInterval) * time.Second))
+
+	return clock.StartPrice - steps*clock.Decrement
+}
+
+// checkSealedBid is an internal function that rejects the sealed bid functions for clock auctions
+func checkSealedBid(auction *Auction) error {
+
+	if auction.Clock != nil {
+		return fmt.Errorf("auction is a clock auction, units are bought using AcceptPrice")
+	}
+
+	return nil
+}
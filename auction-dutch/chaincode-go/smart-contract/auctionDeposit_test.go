@@ -0,0 +1,209 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/chaincodetest"
+	"github.com/stretchr/testify/require"
+)
+
+const depositAuctionID = "auction1"
+
+var (
+	issuer  = chaincodetest.NewClientIdentity("Org1MSP", "issuer")
+	seller  = chaincodetest.NewClientIdentity("Org1MSP", "seller")
+	bidder1 = chaincodetest.NewClientIdentity("Org1MSP", "bidder1")
+	bidder2 = chaincodetest.NewClientIdentity("Org2MSP", "bidder2")
+	bidder3 = chaincodetest.NewClientIdentity("Org2MSP", "bidder3")
+)
+
+func init() {
+	issuer.Attributes = map[string]string{"hf.Type": "admin"}
+}
+
+// auctionTest runs the transactions of an auction of 50 tickets with a deposit of 10 units.
+// Bids can be submitted for 10 minutes, revealed for 10 more minutes, and the winners have
+// 10 more minutes to pay. Each bidder is issued 1000 units
+type auctionTest struct {
+	t        *testing.T
+	stub     *chaincodetest.Stub
+	ctx      *contractapi.TransactionContext
+	contract SmartContract
+	start    time.Time
+}
+
+func newAuctionTest(t *testing.T) *auctionTest {
+	a := &auctionTest{
+		t:     t,
+		stub:  chaincodetest.NewStub("auction"),
+		ctx:   &contractapi.TransactionContext{},
+		start: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	a.ctx.SetStub(a.stub)
+
+	for _, bidder := range []*chaincodetest.ClientIdentity{bidder1, bidder2, bidder3} {
+		a.as(issuer, 0, nil)
+		require.NoError(t, a.contract.Mint(a.ctx, bidder.ID(), 1000))
+	}
+
+	return a
+}
+
+func (a *auctionTest) createAuction() {
+	a.as(seller, 0, nil)
+	err := a.contract.CreateAuction(a.ctx, depositAuctionID, "tickets", 50, "noAuditor", 10,
+		a.start.Add(10*time.Minute).Format(time.RFC3339),
+		a.start.Add(20*time.Minute).Format(time.RFC3339),
+		a.start.Add(30*time.Minute).Format(time.RFC3339))
+	require.NoError(a.t, err)
+}
+
+// as starts a new transaction that is submitted by the client the number of minutes after
+// the auction was created, and endorsed by the peer of the client
+func (a *auctionTest) as(client *chaincodetest.ClientIdentity, minutes int, transient map[string][]byte) {
+	a.stub.StartTransaction(a.start.Add(time.Duration(minutes)*time.Minute), transient)
+	a.ctx.SetClientIdentity(client)
+	client.UsePeerOf()
+}
+
+// bidTransient returns the transient map with the bid of the client
+func bidTransient(client *chaincodetest.ClientIdentity, quantity int, price int) map[string][]byte {
+	bidJSON, _ := json.Marshal(FullBid{
+		Type:     bidKeyType,
+		Quantity: quantity,
+		Price:    price,
+		Org:      client.MSPID,
+		Buyer:    client.ID(),
+		Salt:     "a94a8fe5ccb19ba61c4c0873d391e987",
+	})
+	return map[string][]byte{"bid": bidJSON}
+}
+
+// bid creates a bid of the client and adds it to the auction. It returns the ID of the bid
+func (a *auctionTest) bid(client *chaincodetest.ClientIdentity, quantity int, price int) string {
+	a.as(client, 1, bidTransient(client, quantity, price))
+	txID, err := a.contract.Bid(a.ctx, depositAuctionID)
+	require.NoError(a.t, err)
+
+	a.as(client, 2, nil)
+	require.NoError(a.t, a.contract.SubmitBid(a.ctx, depositAuctionID, txID))
+	return txID
+}
+
+func (a *auctionTest) reveal(client *chaincodetest.ClientIdentity, txID string, quantity int, price int) {
+	a.as(client, 12, bidTransient(client, quantity, price))
+	require.NoError(a.t, a.contract.RevealBid(a.ctx, depositAuctionID, txID))
+}
+
+func (a *auctionTest) requireBalance(client *chaincodetest.ClientIdentity, amount int) {
+	balance, err := a.contract.QueryBalance(a.ctx, client.ID())
+	require.NoError(a.t, err)
+	require.Equal(a.t, amount, balance)
+}
+
+func (a *auctionTest) requirePayment(buyer *chaincodetest.ClientIdentity, status string, amount int, deposit int) {
+	payment, err := a.contract.QueryPayment(a.ctx, depositAuctionID, buyer.ID())
+	require.NoError(a.t, err)
+	require.Equal(a.t, status, payment.Status)
+	require.Equal(a.t, amount, payment.Amount)
+	require.Equal(a.t, deposit, payment.Deposit)
+}
+
+func TestSubmitBidEscrowsDepositAndWithdrawBidRefundsIt(t *testing.T) {
+	a := newAuctionTest(t)
+	a.createAuction()
+
+	txID := a.bid(bidder2, 10, 50)
+	a.requireBalance(bidder2, 990)
+
+	a.as(bidder2, 3, bidTransient(bidder2, 10, 50))
+	require.NoError(t, a.contract.WithdrawBid(a.ctx, depositAuctionID, txID))
+	a.requireBalance(bidder2, 1000)
+}
+
+// endedAuction ends an auction in which bidder1 wins 40 tickets and bidder2 wins the remaining
+// 10 tickets at a price of 20. The second bid of bidder1 loses, and bidder3 does not reveal
+func endedAuction(t *testing.T) *auctionTest {
+	a := newAuctionTest(t)
+	a.createAuction()
+
+	highBid := a.bid(bidder1, 40, 30)
+	lowBid := a.bid(bidder1, 20, 10)
+	clearingBid := a.bid(bidder2, 30, 20)
+	a.bid(bidder3, 50, 40)
+	a.reveal(bidder1, highBid, 40, 30)
+	a.reveal(bidder1, lowBid, 20, 10)
+	a.reveal(bidder2, clearingBid, 30, 20)
+
+	a.as(seller, 21, nil)
+	require.NoError(t, a.contract.EndAuction(a.ctx, depositAuctionID))
+	return a
+}
+
+func TestEndAuctionRefundsLosingBidsAndForfeitsUnrevealedDeposits(t *testing.T) {
+	a := endedAuction(t)
+
+	// each winner keeps the deposit of their winning bid
+	a.requireBalance(bidder1, 990)
+	a.requireBalance(bidder2, 990)
+	a.requireBalance(bidder3, 990)
+	a.requireBalance(seller, 10)
+	a.requirePayment(bidder1, "pending", 800, 10)
+	a.requirePayment(bidder2, "pending", 200, 10)
+
+	a.as(bidder1, 22, nil)
+	require.NoError(t, a.contract.PayAuction(a.ctx, depositAuctionID))
+	a.requireBalance(bidder1, 200)
+	a.requireBalance(seller, 810)
+	a.requirePayment(bidder1, "paid", 800, 10)
+
+	a.as(bidder3, 22, nil)
+	err := a.contract.PayAuction(a.ctx, depositAuctionID)
+	require.EqualError(t, err, "no payment has been recorded for buyer "+bidder3.ID()+" in auction auction1")
+}
+
+func TestForfeitDepositAfterPaymentDeadline(t *testing.T) {
+	a := endedAuction(t)
+
+	a.as(seller, 29, nil)
+	err := a.contract.ForfeitDeposit(a.ctx, depositAuctionID, bidder2.ID())
+	require.EqualError(t, err, "cannot forfeit deposit before the payment deadline 2021-01-01T12:30:00Z")
+
+	a.as(seller, 30, nil)
+	require.NoError(t, a.contract.ForfeitDeposit(a.ctx, depositAuctionID, bidder2.ID()))
+	a.requireBalance(bidder2, 990)
+	a.requireBalance(seller, 20)
+	a.requirePayment(bidder2, "forfeited", 200, 10)
+
+	a.as(bidder2, 31, nil)
+	err = a.contract.PayAuction(a.ctx, depositAuctionID)
+	require.EqualError(t, err, "payment of auction auction1 is not pending")
+}
+
+func TestAcceptPricePaysSellerFromBalanceOfBuyer(t *testing.T) {
+	a := newAuctionTest(t)
+
+	a.as(seller, 0, nil)
+	err := a.contract.CreateClockAuction(a.ctx, depositAuctionID, "tickets", 50, "noAuditor", 100, 10, 60,
+		a.start.Add(5*time.Minute).Format(time.RFC3339))
+	require.NoError(t, err)
+
+	// the price drops by 10 each minute
+	a.as(bidder2, 2, nil)
+	require.NoError(t, a.contract.AcceptPrice(a.ctx, depositAuctionID, 10))
+	a.requireBalance(bidder2, 200)
+	a.requireBalance(seller, 800)
+	a.requirePayment(bidder2, "paid", 800, 0)
+
+	a.as(bidder2, 3, nil)
+	err = a.contract.AcceptPrice(a.ctx, depositAuctionID, 10)
+	require.EqualError(t, err, "insufficient balance, account "+bidder2.ID()+" holds 200 units and needs 700")
+	a.requireBalance(bidder2, 200)
+}
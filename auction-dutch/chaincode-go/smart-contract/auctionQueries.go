@@ -10,6 +10,7 @@ import (
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
 )
 
 // QueryAuction allows all members of the channel to read a public auction
@@ -70,7 +71,7 @@ func (s *SmartContract) QueryBid(ctx contractapi.TransactionContextInterface, au
 	}
 
 	// check that the client querying the bid is the bid owner
-	if bid.Buyer != clientID {
+	if !identity.Same(bid.Buyer, clientID) {
 		return nil, fmt.Errorf("Permission denied, client id %v is not the owner of the bid", clientID)
 	}
 
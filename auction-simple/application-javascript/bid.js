@@ -8,6 +8,7 @@
 
 const { Gateway, Wallets } = require('fabric-network');
 const path = require('path');
+const crypto = require('crypto');
 const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString} = require('../../test-application/javascript/AppUtil.js');
 
 const myChannel = 'mychannel';
@@ -29,7 +30,8 @@ async function bid(ccp,wallet,user,orgMSP,auctionID,price) {
 		let bidder = await contract.evaluateTransaction('GetSubmittingClientIdentity');
 		console.log('*** Result:  Bidder ID is ' + bidder.toString());
 
-		let bidData = { objectType: 'bid', price: parseInt(price), org: orgMSP, bidder: bidder.toString()};
+		// the random salt prevents other organizations from guessing the price from the hash of the bid
+		let bidData = { objectType: 'bid', price: parseInt(price), org: orgMSP, bidder: bidder.toString(), salt: crypto.randomBytes(32).toString('hex')};
 
 		let statefulTxn = contract.createTransaction('Bid');
 		statefulTxn.setEndorsingOrganizations(orgMSP);
@@ -8,12 +8,13 @@
 
 const { Gateway, Wallets } = require('fabric-network');
 const path = require('path');
+const crypto = require('crypto');
 const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString} = require('../../test-application/javascript/AppUtil.js');
 
 const myChannel = 'mychannel';
 const myChaincodeName = 'auction';
 
-async function createAuction(ccp,wallet,user,auctionID,item) {
+async function createAuction(ccp,wallet,user,auctionID,item,biddingMinutes,revealMinutes,auctionType,minimumBid,reservePrice,assetID,deposit,paymentMinutes) {
 	try {
 
 		const gateway = new Gateway();
@@ -25,10 +26,32 @@ async function createAuction(ccp,wallet,user,auctionID,item) {
 		const network = await gateway.getNetwork(myChannel);
 		const contract = network.getContract(myChaincodeName);
 
+		// bids can be submitted until the bidding deadline, and revealed until the reveal deadline
+		let now = Date.now();
+		let biddingDeadline = new Date(now + parseInt(biddingMinutes) * 60000).toISOString();
+		let revealDeadline = new Date(now + (parseInt(biddingMinutes) + parseInt(revealMinutes)) * 60000).toISOString();
+
+		// the winner of an auction with a deposit needs to pay before the payment deadline
+		let paymentDeadline = '';
+		if (parseInt(deposit) > 0) {
+			paymentDeadline = new Date(now + (parseInt(biddingMinutes) + parseInt(revealMinutes) + parseInt(paymentMinutes)) * 60000).toISOString();
+		}
+
 		let statefulTxn = contract.createTransaction('CreateAuction');
 
+		// the reserve price is stored in the implicit collection of the seller,
+		// so the transaction is endorsed by the seller's organization
+		if (reservePrice !== undefined && parseInt(reservePrice) > 0) {
+			let reserveData = { price: parseInt(reservePrice), salt: crypto.randomBytes(32).toString('hex') };
+			statefulTxn.setTransient({
+				reserve: Buffer.from(JSON.stringify(reserveData))
+			});
+			let orgMSP = await contract.evaluateTransaction('WhoAmI');
+			statefulTxn.setEndorsingOrganizations(JSON.parse(orgMSP.toString()).mspID);
+		}
+
 		console.log('\n--> Submit Transaction: Propose a new auction');
-		await statefulTxn.submit(auctionID,item);
+		await statefulTxn.submit(auctionID,item,assetID,auctionType,minimumBid,deposit,biddingDeadline,revealDeadline,paymentDeadline);
 		console.log('*** Result: committed');
 
 		console.log('\n--> Evaluate Transaction: query the auction that was just created');
@@ -45,8 +68,9 @@ async function main() {
 	try {
 
 		if (process.argv[2] === undefined || process.argv[3] === undefined ||
-            process.argv[4] === undefined || process.argv[5] === undefined) {
-			console.log('Usage: node createAuction.js org userID auctionID item');
+            process.argv[4] === undefined || process.argv[5] === undefined ||
+            process.argv[6] === undefined || process.argv[7] === undefined) {
+			console.log('Usage: node createAuction.js org userID auctionID item biddingMinutes revealMinutes [firstPrice|secondPrice] [minimumBid] [reservePrice] [assetID] [deposit paymentMinutes]');
 			process.exit(1);
 		}
 
@@ -54,20 +78,33 @@ async function main() {
 		const user = process.argv[3];
 		const auctionID = process.argv[4];
 		const item = process.argv[5];
+		const biddingMinutes = process.argv[6];
+		const revealMinutes = process.argv[7];
+		const auctionType = process.argv[8] === undefined ? 'firstPrice' : process.argv[8];
+		const minimumBid = process.argv[9] === undefined ? '0' : process.argv[9];
+		const reservePrice = process.argv[10];
+		const assetID = process.argv[11] === undefined ? '' : process.argv[11];
+		const deposit = process.argv[12] === undefined ? '0' : process.argv[12];
+		const paymentMinutes = process.argv[13] === undefined ? '0' : process.argv[13];
+
+		if (parseInt(deposit) > 0 && !(parseInt(paymentMinutes) > 0)) {
+			console.log('An auction with a deposit requires the number of minutes the winner has to pay');
+			process.exit(1);
+		}
 
 		if (org === 'Org1' || org === 'org1') {
 			const ccp = buildCCPOrg1();
 			const walletPath = path.join(__dirname, 'wallet/org1');
 			const wallet = await buildWallet(Wallets, walletPath);
-			await createAuction(ccp,wallet,user,auctionID,item);
+			await createAuction(ccp,wallet,user,auctionID,item,biddingMinutes,revealMinutes,auctionType,minimumBid,reservePrice,assetID,deposit,paymentMinutes);
 		}
 		else if (org === 'Org2' || org === 'org2') {
 			const ccp = buildCCPOrg2();
 			const walletPath = path.join(__dirname, 'wallet/org2');
 			const wallet = await buildWallet(Wallets, walletPath);
-			await createAuction(ccp,wallet,user,auctionID,item);
+			await createAuction(ccp,wallet,user,auctionID,item,biddingMinutes,revealMinutes,auctionType,minimumBid,reservePrice,assetID,deposit,paymentMinutes);
 		}  else {
-			console.log('Usage: node createAuction.js org userID auctionID item');
+			console.log('Usage: node createAuction.js org userID auctionID item biddingMinutes revealMinutes [firstPrice|secondPrice] [minimumBid] [reservePrice] [assetID] [deposit paymentMinutes]');
 			console.log('Org must be Org1 or Org2');
 		}
 	} catch (error) {
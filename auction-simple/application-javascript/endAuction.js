@@ -31,6 +31,20 @@ async function endAuction(ccp,wallet,user,auctionID) {
 
 		let statefulTxn = contract.createTransaction('EndAuction');
 
+		// reveal the reserve price stored in the implicit collection of the seller. Only the
+		// seller can read the reserve price. If the auction is ended by another user after the
+		// reveal deadline, the reserve price is not revealed and the auction ends with no sale
+		if (auctionJSON.reserveHash !== undefined) {
+			try {
+				let reserveString = await contract.evaluateTransaction('QueryReservePrice',auctionID);
+				statefulTxn.setTransient({
+					reserve: Buffer.from(reserveString.toString())
+				});
+			} catch (error) {
+				console.log('*** Reserve price not revealed: ' + error);
+			}
+		}
+
 		if (auctionJSON.organizations.length === 2) {
 			statefulTxn.setEndorsingOrganizations(auctionJSON.organizations[0],auctionJSON.organizations[1]);
 		} else {
@@ -0,0 +1,90 @@
+/*
+ * Copyright IBM Corp. All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+'use strict';
+
+const { Gateway, Wallets } = require('fabric-network');
+const path = require('path');
+const { buildCCPOrg1, buildCCPOrg2, buildWallet, prettyJSONString} = require('../../test-application/javascript/AppUtil.js');
+
+const myChannel = 'mychannel';
+const myChaincodeName = 'auction';
+
+async function payAuction(ccp,wallet,user,auctionID,forfeit) {
+	try {
+
+		const gateway = new Gateway();
+
+		//connect using Discovery enabled
+		await gateway.connect(ccp,
+			{ wallet: wallet, identity: user, discovery: { enabled: true, asLocalhost: true } });
+
+		const network = await gateway.getNetwork(myChannel);
+		const contract = network.getContract(myChaincodeName);
+
+		// the balances of the winner and the seller are endorsed by the organizations of the auction
+		let auctionString = await contract.evaluateTransaction('QueryAuction',auctionID);
+		let auctionJSON = JSON.parse(auctionString);
+
+		if (forfeit) {
+			console.log('\n--> Submit Transaction: forfeit the deposit of the winner after the payment deadline');
+			let statefulTxn = contract.createTransaction('ForfeitDeposit');
+			statefulTxn.setEndorsingOrganizations(...auctionJSON.organizations);
+			await statefulTxn.submit(auctionID);
+		} else {
+			console.log('\n--> Submit Transaction: pay the price of the auction');
+			let statefulTxn = contract.createTransaction('PayAuction');
+			statefulTxn.setEndorsingOrganizations(...auctionJSON.organizations);
+			await statefulTxn.submit(auctionID);
+		}
+		console.log('*** Result: committed');
+
+		console.log('\n--> Evaluate Transaction: query the payment of the auction');
+		let result = await contract.evaluateTransaction('QueryPayment',auctionID);
+		console.log('*** Result: Payment: ' + prettyJSONString(result.toString()));
+
+		gateway.disconnect();
+	} catch (error) {
+		console.error(`******** FAILED to pay auction: ${error}`);
+	}
+}
+
+async function main() {
+	try {
+
+		if (process.argv[2] === undefined || process.argv[3] === undefined ||
+            process.argv[4] === undefined) {
+			console.log('Usage: node payAuction.js org userID auctionID [forfeit]');
+			process.exit(1);
+		}
+
+		const org = process.argv[2];
+		const user = process.argv[3];
+		const auctionID = process.argv[4];
+		const forfeit = process.argv[5] === 'forfeit';
+
+		if (org === 'Org1' || org === 'org1') {
+			const ccp = buildCCPOrg1();
+			const walletPath = path.join(__dirname, 'wallet/org1');
+			const wallet = await buildWallet(Wallets, walletPath);
+			await payAuction(ccp,wallet,user,auctionID,forfeit);
+		}
+		else if (org === 'Org2' || org === 'org2') {
+			const ccp = buildCCPOrg2();
+			const walletPath = path.join(__dirname, 'wallet/org2');
+			const wallet = await buildWallet(Wallets, walletPath);
+			await payAuction(ccp,wallet,user,auctionID,forfeit);
+		}  else {
+			console.log('Usage: node payAuction.js org userID auctionID [forfeit]');
+			console.log('Org must be Org1 or Org2');
+		}
+	} catch (error) {
+		console.error(`******** FAILED to run the application: ${error}`);
+	}
+}
+
+
+main();
@@ -9,8 +9,11 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/balance"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
 )
 
 type SmartContract struct {
@@ -19,15 +22,25 @@ type SmartContract struct {
 
 // Auction data
 type Auction struct {
-	Type         string             `json:"objectType"`
-	ItemSold     string             `json:"item"`
-	Seller       string             `json:"seller"`
-	Orgs         []string           `json:"organizations"`
-	PrivateBids  map[string]BidHash `json:"privateBids"`
-	RevealedBids map[string]FullBid `json:"revealedBids"`
-	Winner       string             `json:"winner"`
-	Price        int                `json:"price"`
-	Status       string             `json:"status"`
+	Type            string             `json:"objectType"`
+	ItemSold        string             `json:"item"`
+	AssetID         string             `json:"assetID,omitempty"`
+	AuctionType     string             `json:"auctionType"`
+	MinimumBid      int                `json:"minimumBid"`
+	ReserveHash     string             `json:"reserveHash,omitempty"`
+	ReservePrice    int                `json:"reservePrice,omitempty"`
+	Seller          string             `json:"seller"`
+	Orgs            []string           `json:"organizations"`
+	PrivateBids     map[string]BidHash `json:"privateBids"`
+	RevealedBids    map[string]FullBid `json:"revealedBids"`
+	Winner          string             `json:"winner"`
+	Price           int                `json:"price"`
+	Status          string             `json:"status"`
+	CreatedAt       time.Time          `json:"createdAt"`
+	BiddingDeadline time.Time          `json:"biddingDeadline"`
+	RevealDeadline  time.Time          `json:"revealDeadline"`
+	Deposit         int                `json:"deposit"`
+	PaymentDeadline time.Time          `json:"paymentDeadline"`
 }
 
 // FullBid is the structure of a revealed bid
@@ -36,19 +49,84 @@ type FullBid struct {
 	Price  int    `json:"price"`
 	Org    string `json:"org"`
 	Bidder string `json:"bidder"`
+	Salt   string `json:"salt,omitempty"`
 }
 
 // BidHash is the structure of a private bid
 type BidHash struct {
-	Org  string `json:"org"`
-	Hash string `json:"hash"`
+	Org       string `json:"org"`
+	Hash      string `json:"hash"`
+	Depositor string `json:"depositor,omitempty"`
+}
+
+// ReservePrice is the structure of the reserve price of the seller. The salt prevents
+// other organizations from guessing the price from the hash on the auction
+type ReservePrice struct {
+	Price int    `json:"price"`
+	Salt  string `json:"salt"`
 }
 
 const bidKeyType = "bid"
+const reserveKeyType = "reserve"
+
+// minSaltLength is the minimum length of the random salt that is added to each bid and to the
+// reserve price, so that the price cannot be guessed from the hash that is added to the auction
+const minSaltLength = 16
+
+// Auction types. In a first price auction the winner pays the price of their bid,
+// in a second price auction the winner pays the price of the second highest bid
+const (
+	firstPriceAuction  = "firstPrice"
+	secondPriceAuction = "secondPrice"
+)
 
 // CreateAuction creates on auction on the public channel. The identity that
-// submits the transacion becomes the seller of the auction
-func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterface, auctionID string, itemsold string) error {
+// submits the transacion becomes the seller of the auction. The auction type
+// is either firstPrice or secondPrice. Bids below the minimum bid cannot be revealed.
+// The seller can pass a hidden reserve price using the "reserve" key of the transient map,
+// which is stored in the implicit collection of the seller's organization.
+// Only the hash of the reserve price is added to the auction. Bids can be submitted
+// until the bidding deadline, and revealed until the reveal deadline. Both deadlines
+// are RFC3339 timestamps that are compared with the timestamp of the transactions.
+// If an asset ID is provided, the asset owned by the seller is locked until the auction
+// ends, and is transferred to the winner when the auction ends.
+// If the deposit is greater than 0, each bid needs to escrow the deposit from the balance of
+// the bidder when it is submitted. The deposit of the winner is applied to the price, and the
+// winner needs to pay the rest before the payment deadline or the deposit is forfeited
+func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterface, auctionID string, itemsold string, assetID string, auctionType string, minimumBid int, deposit int, biddingDeadline string, revealDeadline string, paymentDeadline string) error {
+
+	if auctionType != firstPriceAuction && auctionType != secondPriceAuction {
+		return fmt.Errorf("auction type must be %s or %s", firstPriceAuction, secondPriceAuction)
+	}
+	if minimumBid < 0 {
+		return fmt.Errorf("minimum bid cannot be negative")
+	}
+	// a second price auction with a single revealed bid clears at the minimum bid
+	if auctionType == secondPriceAuction && minimumBid <= 0 {
+		return fmt.Errorf("second price auctions require a positive minimum bid")
+	}
+	if deposit < 0 {
+		return fmt.Errorf("deposit cannot be negative")
+	}
+
+	biddingDeadlineTime, revealDeadlineTime, err := parseDeadlines(ctx, biddingDeadline, revealDeadline)
+	if err != nil {
+		return err
+	}
+
+	// the payment deadline is only used by auctions with a deposit
+	var paymentDeadlineTime time.Time
+	if deposit > 0 {
+		paymentDeadlineTime, err = parsePaymentDeadline(paymentDeadline, revealDeadlineTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	createdAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
 
 	// get ID of submitting client
 	clientID, err := s.GetSubmittingClientIdentity(ctx)
@@ -62,21 +140,44 @@ func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterfac
 		return fmt.Errorf("failed to get client identity %v", err)
 	}
 
+	// lock the asset sold in the auction, so that the seller cannot transfer it during bidding
+	if assetID != "" {
+		err = lockAsset(ctx, assetID, auctionID, clientID)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Create auction
 	bidders := make(map[string]BidHash)
 	revealedBids := make(map[string]FullBid)
 
 	auction := Auction{
-		Type:         "auction",
-		ItemSold:     itemsold,
-		Price:        0,
-		Seller:       clientID,
-		Orgs:         []string{clientOrgID},
-		PrivateBids:  bidders,
-		RevealedBids: revealedBids,
-		Winner:       "",
-		Status:       "open",
+		Type:            "auction",
+		ItemSold:        itemsold,
+		AssetID:         assetID,
+		AuctionType:     auctionType,
+		MinimumBid:      minimumBid,
+		Price:           0,
+		Seller:          clientID,
+		Orgs:            []string{clientOrgID},
+		PrivateBids:     bidders,
+		RevealedBids:    revealedBids,
+		Winner:          "",
+		Status:          "open",
+		CreatedAt:       createdAt,
+		BiddingDeadline: biddingDeadlineTime,
+		RevealDeadline:  revealDeadlineTime,
+		Deposit:         deposit,
+		PaymentDeadline: paymentDeadlineTime,
+	}
+
+	// store the reserve price in the implicit collection of the seller, if there is one
+	reserveHash, err := putReservePrice(ctx, auctionID)
+	if err != nil {
+		return err
 	}
+	auction.ReserveHash = reserveHash
 
 	auctionJSON, err := json.Marshal(auction)
 	if err != nil {
@@ -89,6 +190,12 @@ func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterfac
 		return fmt.Errorf("failed to put auction in public data: %v", err)
 	}
 
+	// index the auction so that it can be found using ListAuctions
+	err = putAuctionIndexes(ctx, auctionID, &auction)
+	if err != nil {
+		return err
+	}
+
 	// set the seller of the auction as an endorser
 	err = setAssetStateBasedEndorsement(ctx, auctionID, clientOrgID)
 	if err != nil {
@@ -114,6 +221,12 @@ func (s *SmartContract) Bid(ctx contractapi.TransactionContextInterface, auction
 		return "", fmt.Errorf("bid key not found in the transient map")
 	}
 
+	// the bid needs to include a random salt, and is stored in a canonical form
+	BidJSON, err = marshalBid(BidJSON)
+	if err != nil {
+		return "", err
+	}
+
 	// get the implicit collection name using the bidder's organization ID
 	collection, err := getCollectionName(ctx)
 	if err != nil {
@@ -147,15 +260,10 @@ func (s *SmartContract) Bid(ctx contractapi.TransactionContextInterface, auction
 
 // SubmitBid is used by the bidder to add the hash of that bid stored in private data to the
 // auction. Note that this function alters the auction in private state, and needs
-// to meet the auction endorsement policy. Transaction ID is used identify the bid
+// to meet the auction endorsement policy. Transaction ID is used identify the bid.
+// If the auction requires a deposit, the deposit is taken from the balance of the submitting client
 func (s *SmartContract) SubmitBid(ctx contractapi.TransactionContextInterface, auctionID string, txID string) error {
 
-	// get the MSP ID of the bidder's org
-	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return fmt.Errorf("failed to get client MSP ID: %v", err)
-	}
-
 	// get the auction from public state
 	auction, err := s.QueryAuction(ctx, auctionID)
 	if err != nil {
@@ -168,47 +276,30 @@ func (s *SmartContract) SubmitBid(ctx contractapi.TransactionContextInterface, a
 		return fmt.Errorf("cannot join closed or ended auction")
 	}
 
-	// get the inplicit collection name of bidder's org
-	collection, err := getCollectionName(ctx)
+	// bids cannot be added after the bidding deadline, even if the auction was not closed
+	txTime, err := getTxTimestamp(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get implicit collection name: %v", err)
+		return err
 	}
-
-	// use the transaction ID passed as a parameter to create composite bid key
-	bidKey, err := ctx.GetStub().CreateCompositeKey(bidKeyType, []string{auctionID, txID})
-	if err != nil {
-		return fmt.Errorf("failed to create composite key: %v", err)
+	if !txTime.Before(auction.BiddingDeadline) {
+		return fmt.Errorf("cannot join auction, bidding deadline %s has passed", auction.BiddingDeadline.Format(time.RFC3339))
 	}
 
-	// get the hash of the bid stored in private data collection
-	bidHash, err := ctx.GetStub().GetPrivateDataHash(collection, bidKey)
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to read bid bash from collection: %v", err)
-	}
-	if bidHash == nil {
-		return fmt.Errorf("bid hash does not exist: %s", bidKey)
+		return fmt.Errorf("failed to get client identity %v", err)
 	}
 
-	// store the hash along with the bidder's organization
-	NewHash := BidHash{
-		Org:  clientOrgID,
-		Hash: fmt.Sprintf("%x", bidHash),
+	err = addPrivateBid(ctx, auctionID, auction, txID, clientID)
+	if err != nil {
+		return err
 	}
 
-	bidders := make(map[string]BidHash)
-	bidders = auction.PrivateBids
-	bidders[bidKey] = NewHash
-	auction.PrivateBids = bidders
-
-	// Add the bidding organization to the list of participating organizations if it is not already
-	Orgs := auction.Orgs
-	if !(contains(Orgs, clientOrgID)) {
-		newOrgs := append(Orgs, clientOrgID)
-		auction.Orgs = newOrgs
-
-		err = addAssetStateBasedEndorsement(ctx, auctionID, clientOrgID)
+	// escrow the deposit until the auction ends or the bid is withdrawn
+	if auction.Deposit > 0 {
+		err = balance.Apply(ctx.GetStub(), map[string]int{clientID: -auction.Deposit})
 		if err != nil {
-			return fmt.Errorf("failed setting state based endorsement for new organization: %v", err)
+			return fmt.Errorf("failed to escrow deposit: %v", err)
 		}
 	}
 
@@ -236,6 +327,13 @@ func (s *SmartContract) RevealBid(ctx contractapi.TransactionContextInterface, a
 		return fmt.Errorf("bid key not found in the transient map")
 	}
 
+	// the revealed bid needs to include the salt, and is hashed in the
+	// same canonical form as the bid that was stored
+	bidJSON, err := marshalBid(transientBidJSON)
+	if err != nil {
+		return err
+	}
+
 	// get implicit collection name of organization ID
 	collection, err := getCollectionName(ctx)
 	if err != nil {
@@ -266,18 +364,29 @@ func (s *SmartContract) RevealBid(ctx contractapi.TransactionContextInterface, a
 	// Complete a series of three checks before we add the bid to the auction
 
 	// check 1: check that the auction is closed. We cannot reveal a
-	// bid to an open auction
+	// bid to an open auction. The auction is closed once the bidding deadline
+	// has passed, and bids cannot be revealed after the reveal deadline
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	previousStatus := auction.Status
+	closeAfterBiddingDeadline(auction, txTime)
+
 	Status := auction.Status
 	if Status != "closed" {
 		return fmt.Errorf("cannot reveal bid for open or ended auction")
 	}
+	if !txTime.Before(auction.RevealDeadline) {
+		return fmt.Errorf("cannot reveal bid, reveal deadline %s has passed", auction.RevealDeadline.Format(time.RFC3339))
+	}
 
 	// check 2: check that hash of revealed bid matches hash of private bid
 	// on the public ledger. This checks that the bidder is telling the truth
 	// about the value of their bid
 
 	hash := sha256.New()
-	hash.Write(transientBidJSON)
+	hash.Write(bidJSON)
 	calculatedBidJSONHash := hash.Sum(nil)
 
 	// verify that the hash of the passed immutable properties matches the on-chain hash
@@ -334,10 +443,15 @@ func (s *SmartContract) RevealBid(ctx contractapi.TransactionContextInterface, a
 	}
 
 	// check 4: make sure that the transaction is being submitted is the bidder
-	if bidInput.Bidder != clientID {
+	if !identity.Same(bidInput.Bidder, clientID) {
 		return fmt.Errorf("Permission denied, client id %v is not the owner of the bid", clientID)
 	}
 
+	// check 5: make sure that the bid is not below the minimum bid of the auction
+	if bidInput.Price < auction.MinimumBid {
+		return fmt.Errorf("bid price %d is below the minimum bid %d", bidInput.Price, auction.MinimumBid)
+	}
+
 	revealedBids := make(map[string]FullBid)
 	revealedBids = auction.RevealedBids
 	revealedBids[bidKey] = NewBid
@@ -351,11 +465,17 @@ func (s *SmartContract) RevealBid(ctx contractapi.TransactionContextInterface, a
 		return fmt.Errorf("failed to update auction: %v", err)
 	}
 
+	err = updateStatusIndex(ctx, auctionID, previousStatus, auction.Status)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// CloseAuction can be used by the seller to close the auction. This prevents
-// bids from being added to the auction, and allows users to reveal their bid
+// CloseAuction can be used by anyone to close the auction after the bidding deadline.
+// This prevents bids from being added to the auction, and allows users to reveal their bid.
+// The auction cannot be closed before the deadline, so that the seller cannot shut out bidders
 func (s *SmartContract) CloseAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
 
 	// get auction from public state
@@ -364,24 +484,19 @@ func (s *SmartContract) CloseAuction(ctx contractapi.TransactionContextInterface
 		return fmt.Errorf("failed to get auction from public state %v", err)
 	}
 
-	// the auction can only be closed by the seller
-
-	// get ID of submitting client
-	clientID, err := s.GetSubmittingClientIdentity(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get client identity %v", err)
-	}
-
-	Seller := auction.Seller
-	if Seller != clientID {
-		return fmt.Errorf("auction can only be closed by seller: %v", err)
-	}
-
 	Status := auction.Status
 	if Status != "open" {
 		return fmt.Errorf("cannot close auction that is not open")
 	}
 
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if txTime.Before(auction.BiddingDeadline) {
+		return fmt.Errorf("cannot close auction before the bidding deadline %s", auction.BiddingDeadline.Format(time.RFC3339))
+	}
+
 	auction.Status = string("closed")
 
 	closedAuctionJSON, _ := json.Marshal(auction)
@@ -391,11 +506,21 @@ func (s *SmartContract) CloseAuction(ctx contractapi.TransactionContextInterface
 		return fmt.Errorf("failed to close auction: %v", err)
 	}
 
+	err = updateStatusIndex(ctx, auctionID, Status, auction.Status)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // EndAuction both changes the auction status to closed and calculates the winners
-// of the auction
+// of the auction. The seller can end the auction after the bidding deadline, and
+// anyone can end the auction after the reveal deadline. If the auction has a reserve
+// price, the seller reveals it using the "reserve" key of the transient map. The auction
+// ends with no sale if the highest bid is below the reserve price, or if the reserve
+// price is not revealed when the auction is ended by someone other than the seller.
+// Bids that were not revealed before the reveal deadline are forfeited
 func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
 
 	// get auction from public state
@@ -404,17 +529,24 @@ func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("failed to get auction from public state %v", err)
 	}
 
-	// Check that the auction is being ended by the seller
-
 	// get ID of submitting client
 	clientID, err := s.GetSubmittingClientIdentity(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get client identity %v", err)
 	}
+	isSeller := identity.Same(auction.Seller, clientID)
 
-	Seller := auction.Seller
-	if Seller != clientID {
-		return fmt.Errorf("auction can only be ended by seller: %v", err)
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	previousStatus := auction.Status
+	closeAfterBiddingDeadline(auction, txTime)
+	revealDeadlinePassed := !txTime.Before(auction.RevealDeadline)
+
+	// Check that the auction is being ended by the seller, unless the reveal deadline has passed
+	if !isSeller && !revealDeadlinePassed {
+		return fmt.Errorf("auction can only be ended by seller before the reveal deadline %s", auction.RevealDeadline.Format(time.RFC3339))
 	}
 
 	Status := auction.Status
@@ -425,30 +557,87 @@ func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface,
 	// get the list of revealed bids
 	revealedBidMap := auction.RevealedBids
 	if len(auction.RevealedBids) == 0 {
-		return fmt.Errorf("No bids have been revealed, cannot end auction: %v", err)
+		if !revealDeadlinePassed {
+			return fmt.Errorf("No bids have been revealed, cannot end auction before the reveal deadline")
+		}
+		auction.Status = string("no sale")
+		return putEndedAuction(ctx, auctionID, auction, previousStatus)
 	}
 
-	// determine the highest bid
-	for _, bid := range revealedBidMap {
-		if bid.Price > auction.Price {
-			auction.Winner = bid.Bidder
-			auction.Price = bid.Price
+	// determine the highest bid. Bids are ranked in the same order on every peer
+	rankedBids := rankRevealedBids(revealedBidMap)
+	auction.Winner = revealedBidMap[rankedBids[0]].Bidder
+	auction.Price = revealedBidMap[rankedBids[0]].Price
+
+	// in a second price auction the winner pays the price of the second highest bid,
+	// or the minimum bid if no other bid was revealed
+	if auction.AuctionType == secondPriceAuction {
+		auction.Price = auction.MinimumBid
+		if len(rankedBids) > 1 {
+			auction.Price = revealedBidMap[rankedBids[1]].Price
 		}
 	}
 
-	// check if there is a winning bid that has yet to be revealed
-	err = checkForHigherBid(ctx, auction.Price, auction.RevealedBids, auction.PrivateBids)
-	if err != nil {
-		return fmt.Errorf("Cannot end auction: %v", err)
+	// check if there is a winning bid that has yet to be revealed. Bids that were
+	// not revealed before the reveal deadline are forfeited and cannot win the auction
+	if !revealDeadlinePassed {
+		err = checkForHigherBid(ctx, auction.Price, auction.RevealedBids, auction.PrivateBids)
+		if err != nil {
+			return fmt.Errorf("Cannot end auction: %v", err)
+		}
 	}
 
 	auction.Status = string("ended")
 
+	// compare the highest bid with the reserve price of the seller
+	if auction.ReserveHash != "" {
+		reserve, err := readRevealedReservePrice(ctx, auction.ReserveHash)
+		if err != nil {
+			return err
+		}
+		if reserve == nil && isSeller {
+			return fmt.Errorf("auction has a reserve price, reserve key not found in the transient map")
+		}
+
+		highestPrice := revealedBidMap[rankedBids[0]].Price
+		if reserve == nil {
+			// the reserve price was not revealed by the seller
+			auction.Winner = ""
+			auction.Price = 0
+			auction.Status = string("no sale")
+		} else if highestPrice < reserve.Price {
+			auction.ReservePrice = reserve.Price
+			auction.Winner = ""
+			auction.Price = 0
+			auction.Status = string("no sale")
+		} else {
+			auction.ReservePrice = reserve.Price
+			// the winner of a second price auction pays at least the reserve price
+			if auction.Price < reserve.Price {
+				auction.Price = reserve.Price
+			}
+		}
+	}
+
+	return putEndedAuction(ctx, auctionID, auction, previousStatus)
+}
+
+// putEndedAuction is an internal function that puts an auction that has ended back into state,
+// and moves the auction from the index entry of its previous status to its final status.
+// The asset sold in the auction is transferred to the winner, or unlocked if there is no sale
+func putEndedAuction(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction, previousStatus string) error {
+
+	err := settleAuction(ctx, auctionID, auction)
+	if err != nil {
+		return err
+	}
+
 	endedAuctionJSON, _ := json.Marshal(auction)
 
 	err = ctx.GetStub().PutState(auctionID, endedAuctionJSON)
 	if err != nil {
 		return fmt.Errorf("failed to end auction: %v", err)
 	}
-	return nil
+
+	return updateStatusIndex(ctx, auctionID, previousStatus, auction.Status)
 }
@@ -0,0 +1,289 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
+)
+
+// Asset is an item that is held by the auction chaincode and can be sold in an auction.
+// While an auction is running, the asset is locked by the auction and cannot be transferred
+type Asset struct {
+	Type        string `json:"objectType"`
+	ID          string `json:"assetID"`
+	Description string `json:"description"`
+	Owner       string `json:"owner"`
+	AuctionID   string `json:"auctionID,omitempty"`
+}
+
+// Payment records the price that the winner of an auction owes to the seller. In auctions
+// with a deposit, the deposit of the winner is applied to the amount, and the status of the
+// payment is pending until the winner pays the remainder or forfeits the deposit
+type Payment struct {
+	Type       string    `json:"objectType"`
+	AuctionID  string    `json:"auctionID"`
+	AssetID    string    `json:"assetID"`
+	Payer      string    `json:"payer"`
+	Payee      string    `json:"payee"`
+	Amount     int       `json:"amount"`
+	Deposit    int       `json:"deposit"`
+	Status     string    `json:"status"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+const assetKeyType = "asset"
+const paymentKeyType = "payment"
+
+// CreateAsset creates an asset that is owned by the submitting client
+func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface, assetID string, description string) error {
+
+	existing, err := readAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("asset %v already exists", assetID)
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	asset := Asset{
+		Type:        assetKeyType,
+		ID:          assetID,
+		Description: description,
+		Owner:       clientID,
+	}
+
+	return putAsset(ctx, &asset)
+}
+
+// ReadAsset allows all members of the channel to read an asset
+func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, assetID string) (*Asset, error) {
+
+	asset, err := readAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if asset == nil {
+		return nil, fmt.Errorf("asset %v does not exist", assetID)
+	}
+
+	return asset, nil
+}
+
+// TransferAsset can be used by the owner of an asset to transfer it to a new owner.
+// Assets that are locked by an auction cannot be transferred
+func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface, assetID string, newOwner string) error {
+
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+	if !identity.Same(asset.Owner, clientID) {
+		return fmt.Errorf("asset %v can only be transferred by its owner", assetID)
+	}
+	if asset.AuctionID != "" {
+		return fmt.Errorf("asset %v is locked by auction %v", assetID, asset.AuctionID)
+	}
+
+	asset.Owner = newOwner
+
+	return putAsset(ctx, asset)
+}
+
+// QueryPayment allows all members of the channel to read the payment recorded when an auction ended
+func (s *SmartContract) QueryPayment(ctx contractapi.TransactionContextInterface, auctionID string) (*Payment, error) {
+
+	paymentKey, err := ctx.GetStub().CreateCompositeKey(paymentKeyType, []string{auctionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	paymentJSON, err := ctx.GetStub().GetState(paymentKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment %v: %v", paymentKey, err)
+	}
+	if paymentJSON == nil {
+		return nil, fmt.Errorf("no payment has been recorded for auction %v", auctionID)
+	}
+
+	var payment *Payment
+	err = json.Unmarshal(paymentJSON, &payment)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// lockAsset is an internal function that locks the asset sold in a new auction, so that
+// the seller cannot transfer the asset while the auction is running
+func lockAsset(ctx contractapi.TransactionContextInterface, assetID string, auctionID string, seller string) error {
+
+	asset, err := readAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if asset == nil {
+		return fmt.Errorf("asset %v does not exist", assetID)
+	}
+	if !identity.Same(asset.Owner, seller) {
+		return fmt.Errorf("asset %v can only be sold by its owner", assetID)
+	}
+	if asset.AuctionID != "" {
+		return fmt.Errorf("asset %v is already being sold in auction %v", assetID, asset.AuctionID)
+	}
+
+	asset.AuctionID = auctionID
+
+	return putAsset(ctx, asset)
+}
+
+// settleAuction is an internal function that settles an auction that has ended. If the
+// auction has a winner, the asset is transferred to the winner and the payment of the
+// price to the seller is recorded. The asset is unlocked in all cases. Auctions with a
+// deposit are settled using the balance ledger
+func settleAuction(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction) error {
+
+	if auction.Deposit > 0 {
+		return settleDeposits(ctx, auctionID, auction)
+	}
+
+	if auction.AssetID == "" {
+		return nil
+	}
+
+	if auction.Status != "ended" || auction.Winner == "" {
+		return releaseAsset(ctx, auction.AssetID, "")
+	}
+
+	recordedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	payment := Payment{
+		Type:       paymentKeyType,
+		AuctionID:  auctionID,
+		AssetID:    auction.AssetID,
+		Payer:      auction.Winner,
+		Payee:      auction.Seller,
+		Amount:     auction.Price,
+		Status:     "recorded",
+		RecordedAt: recordedAt,
+	}
+
+	err = putPayment(ctx, &payment)
+	if err != nil {
+		return err
+	}
+
+	return releaseAsset(ctx, auction.AssetID, auction.Winner)
+}
+
+// releaseAsset is an internal function that unlocks the asset sold in an auction, and
+// transfers it to the new owner if one is provided
+func releaseAsset(ctx contractapi.TransactionContextInterface, assetID string, newOwner string) error {
+
+	if assetID == "" {
+		return nil
+	}
+
+	asset, err := readAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if asset == nil {
+		return fmt.Errorf("asset %v does not exist", assetID)
+	}
+
+	asset.AuctionID = ""
+	if newOwner != "" {
+		asset.Owner = newOwner
+	}
+
+	return putAsset(ctx, asset)
+}
+
+// putPayment is an internal function that puts the payment of an auction into public state
+func putPayment(ctx contractapi.TransactionContextInterface, payment *Payment) error {
+
+	paymentJSON, err := json.Marshal(payment)
+	if err != nil {
+		return err
+	}
+
+	paymentKey, err := ctx.GetStub().CreateCompositeKey(paymentKeyType, []string{payment.AuctionID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(paymentKey, paymentJSON)
+	if err != nil {
+		return fmt.Errorf("failed to record payment: %v", err)
+	}
+
+	return nil
+}
+
+// readAsset is an internal function that reads an asset from public state.
+// It returns nil if the asset does not exist
+func readAsset(ctx contractapi.TransactionContextInterface, assetID string) (*Asset, error) {
+
+	assetKey, err := ctx.GetStub().CreateCompositeKey(assetKeyType, []string{assetID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	assetJSON, err := ctx.GetStub().GetState(assetKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset %v: %v", assetID, err)
+	}
+	if assetJSON == nil {
+		return nil, nil
+	}
+
+	var asset *Asset
+	err = json.Unmarshal(assetJSON, &asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return asset, nil
+}
+
+// putAsset is an internal function that puts an asset into public state
+func putAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+
+	assetKey, err := ctx.GetStub().CreateCompositeKey(assetKeyType, []string{asset.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutState(assetKey, assetJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put asset %v: %v", asset.ID, err)
+	}
+
+	return nil
+}
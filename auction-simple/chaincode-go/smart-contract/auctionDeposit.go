@@ -0,0 +1,177 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/balance"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
+)
+
+// PayAuction is used by the winner of an auction with a deposit to pay the price before
+// the payment deadline. The deposit of the winner is applied to the price, and the rest
+// is taken from the balance of the winner. The price is added to the balance of the seller,
+// and the asset sold in the auction is transferred to the winner
+func (s *SmartContract) PayAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	payment, err := s.QueryPayment(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+	if payment.Status != "pending" {
+		return fmt.Errorf("payment of auction %v is not pending", auctionID)
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+	if !identity.Same(payment.Payer, clientID) {
+		return fmt.Errorf("auction can only be paid by the winner")
+	}
+
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if !txTime.Before(auction.PaymentDeadline) {
+		return fmt.Errorf("cannot pay auction, payment deadline %s has passed", auction.PaymentDeadline.Format(time.RFC3339))
+	}
+
+	changes := make(map[string]int)
+	changes[payment.Payer] -= payment.Amount - payment.Deposit
+	changes[payment.Payee] += payment.Amount
+
+	err = balance.Apply(ctx.GetStub(), changes)
+	if err != nil {
+		return err
+	}
+
+	err = releaseAsset(ctx, auction.AssetID, payment.Payer)
+	if err != nil {
+		return err
+	}
+
+	payment.Status = "paid"
+
+	return putPayment(ctx, payment)
+}
+
+// ForfeitDeposit can be used by anyone after the payment deadline of an auction that the
+// winner did not pay. The deposit of the winner is added to the balance of the seller,
+// and the asset sold in the auction is unlocked and stays with the seller
+func (s *SmartContract) ForfeitDeposit(ctx contractapi.TransactionContextInterface, auctionID string) error {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	payment, err := s.QueryPayment(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+	if payment.Status != "pending" {
+		return fmt.Errorf("payment of auction %v is not pending", auctionID)
+	}
+
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if txTime.Before(auction.PaymentDeadline) {
+		return fmt.Errorf("cannot forfeit deposit before the payment deadline %s", auction.PaymentDeadline.Format(time.RFC3339))
+	}
+
+	err = balance.Apply(ctx.GetStub(), map[string]int{payment.Payee: payment.Deposit})
+	if err != nil {
+		return err
+	}
+
+	err = releaseAsset(ctx, auction.AssetID, "")
+	if err != nil {
+		return err
+	}
+
+	payment.Status = "forfeited"
+
+	return putPayment(ctx, payment)
+}
+
+// settleDeposits is an internal function that settles an auction with a deposit when the
+// auction ends. The deposits of the revealed bids are refunded, except for the deposit of one
+// bid of the winner, which is applied to the price. The deposits of the bids that were not
+// revealed are forfeited to the seller. If the deposit covers the price, the auction is paid
+// right away. Otherwise the payment is pending until the winner pays the rest
+func settleDeposits(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction) error {
+
+	changes := make(map[string]int)
+	winnerDeposit := 0
+	for bidKey, privateBid := range auction.PrivateBids {
+		if privateBid.Depositor == "" {
+			continue
+		}
+		if _, revealed := auction.RevealedBids[bidKey]; !revealed {
+			changes[auction.Seller] += auction.Deposit
+			continue
+		}
+		if auction.Winner != "" && winnerDeposit == 0 && identity.Same(privateBid.Depositor, auction.Winner) {
+			winnerDeposit = auction.Deposit
+			continue
+		}
+		changes[privateBid.Depositor] += auction.Deposit
+	}
+
+	if auction.Status != "ended" || auction.Winner == "" {
+		err := balance.Apply(ctx.GetStub(), changes)
+		if err != nil {
+			return err
+		}
+		return releaseAsset(ctx, auction.AssetID, "")
+	}
+
+	recordedAt, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	payment := Payment{
+		Type:       paymentKeyType,
+		AuctionID:  auctionID,
+		AssetID:    auction.AssetID,
+		Payer:      auction.Winner,
+		Payee:      auction.Seller,
+		Amount:     auction.Price,
+		Deposit:    winnerDeposit,
+		Status:     "pending",
+		RecordedAt: recordedAt,
+	}
+
+	if auction.Price <= winnerDeposit {
+		changes[auction.Winner] += winnerDeposit - auction.Price
+		changes[auction.Seller] += auction.Price
+		payment.Status = "paid"
+
+		err = releaseAsset(ctx, auction.AssetID, auction.Winner)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = balance.Apply(ctx.GetStub(), changes)
+	if err != nil {
+		return err
+	}
+
+	return putPayment(ctx, &payment)
+}
@@ -0,0 +1,224 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/chaincodetest"
+	"github.com/stretchr/testify/require"
+)
+
+const depositAuctionID = "auction1"
+
+var (
+	issuer  = chaincodetest.NewClientIdentity("Org1MSP", "issuer")
+	seller  = chaincodetest.NewClientIdentity("Org1MSP", "seller")
+	bidder1 = chaincodetest.NewClientIdentity("Org1MSP", "bidder1")
+	bidder2 = chaincodetest.NewClientIdentity("Org2MSP", "bidder2")
+	bidder3 = chaincodetest.NewClientIdentity("Org2MSP", "bidder3")
+)
+
+func init() {
+	issuer.Attributes = map[string]string{"hf.Type": "admin"}
+}
+
+// auctionTest runs the transactions of an auction with a deposit of 10 units. Bids can be
+// submitted for 10 minutes, revealed for 10 more minutes, and the winner has 10 more
+// minutes to pay. Each bidder is issued 100 units
+type auctionTest struct {
+	t        *testing.T
+	stub     *chaincodetest.Stub
+	ctx      *contractapi.TransactionContext
+	contract SmartContract
+	start    time.Time
+}
+
+func newAuctionTest(t *testing.T, auctionType string, minimumBid int) *auctionTest {
+	a := &auctionTest{
+		t:     t,
+		stub:  chaincodetest.NewStub("auction"),
+		ctx:   &contractapi.TransactionContext{},
+		start: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	a.ctx.SetStub(a.stub)
+
+	for _, bidder := range []*chaincodetest.ClientIdentity{bidder1, bidder2, bidder3} {
+		a.as(issuer, 0, nil)
+		require.NoError(t, a.contract.Mint(a.ctx, bidder.ID(), 100))
+	}
+
+	a.as(seller, 0, nil)
+	err := a.contract.CreateAuction(a.ctx, depositAuctionID, "painting", "", auctionType, minimumBid, 10,
+		a.start.Add(10*time.Minute).Format(time.RFC3339),
+		a.start.Add(20*time.Minute).Format(time.RFC3339),
+		a.start.Add(30*time.Minute).Format(time.RFC3339))
+	require.NoError(t, err)
+
+	return a
+}
+
+// as starts a new transaction that is submitted by the client the number of minutes after
+// the auction was created, and endorsed by the peer of the client
+func (a *auctionTest) as(client *chaincodetest.ClientIdentity, minutes int, transient map[string][]byte) {
+	a.stub.StartTransaction(a.start.Add(time.Duration(minutes)*time.Minute), transient)
+	a.ctx.SetClientIdentity(client)
+	client.UsePeerOf()
+}
+
+// bidTransient returns the transient map with the bid of the client
+func bidTransient(client *chaincodetest.ClientIdentity, price int) map[string][]byte {
+	bidJSON, _ := json.Marshal(FullBid{
+		Type:   bidKeyType,
+		Price:  price,
+		Org:    client.MSPID,
+		Bidder: client.ID(),
+		Salt:   "a94a8fe5ccb19ba61c4c0873d391e987",
+	})
+	return map[string][]byte{"bid": bidJSON}
+}
+
+// bid creates a bid of the client and adds it to the auction. It returns the ID of the bid
+func (a *auctionTest) bid(client *chaincodetest.ClientIdentity, price int) string {
+	a.as(client, 1, bidTransient(client, price))
+	txID, err := a.contract.Bid(a.ctx, depositAuctionID)
+	require.NoError(a.t, err)
+
+	a.as(client, 2, nil)
+	require.NoError(a.t, a.contract.SubmitBid(a.ctx, depositAuctionID, txID))
+	return txID
+}
+
+func (a *auctionTest) reveal(client *chaincodetest.ClientIdentity, txID string, price int) {
+	a.as(client, 12, bidTransient(client, price))
+	require.NoError(a.t, a.contract.RevealBid(a.ctx, depositAuctionID, txID))
+}
+
+func (a *auctionTest) end() {
+	a.as(seller, 21, nil)
+	require.NoError(a.t, a.contract.EndAuction(a.ctx, depositAuctionID))
+}
+
+func (a *auctionTest) requireBalance(client *chaincodetest.ClientIdentity, amount int) {
+	balance, err := a.contract.QueryBalance(a.ctx, client.ID())
+	require.NoError(a.t, err)
+	require.Equal(a.t, amount, balance)
+}
+
+func (a *auctionTest) requirePayment(status string) *Payment {
+	payment, err := a.contract.QueryPayment(a.ctx, depositAuctionID)
+	require.NoError(a.t, err)
+	require.Equal(a.t, status, payment.Status)
+	return payment
+}
+
+func TestMintRequiresAdminOfIssuer(t *testing.T) {
+	a := newAuctionTest(t, firstPriceAuction, 0)
+
+	a.as(bidder1, 1, nil)
+	err := a.contract.Mint(a.ctx, bidder1.ID(), 100)
+	require.EqualError(t, err, "submitting client is not an admin")
+
+	a.as(bidder2, 1, nil)
+	err = a.contract.Mint(a.ctx, bidder2.ID(), 100)
+	require.EqualError(t, err, "submitting client is not a member of the issuer organization Org1MSP")
+
+	a.requireBalance(bidder1, 100)
+}
+
+func TestSubmitBidEscrowsDepositAndWithdrawBidRefundsIt(t *testing.T) {
+	a := newAuctionTest(t, firstPriceAuction, 0)
+
+	txID := a.bid(bidder2, 50)
+	a.requireBalance(bidder2, 90)
+
+	a.as(bidder2, 3, bidTransient(bidder2, 50))
+	require.NoError(t, a.contract.WithdrawBid(a.ctx, depositAuctionID, txID))
+	a.requireBalance(bidder2, 100)
+}
+
+func TestSubmitBidRequiresDeposit(t *testing.T) {
+	a := newAuctionTest(t, firstPriceAuction, 0)
+	a.as(bidder1, 1, nil)
+	require.NoError(t, a.contract.Transfer(a.ctx, bidder2.ID(), 95))
+
+	a.as(bidder1, 1, bidTransient(bidder1, 50))
+	txID, err := a.contract.Bid(a.ctx, depositAuctionID)
+	require.NoError(t, err)
+
+	a.as(bidder1, 2, nil)
+	err = a.contract.SubmitBid(a.ctx, depositAuctionID, txID)
+	require.Error(t, err)
+	a.requireBalance(bidder1, 5)
+}
+
+func TestEndAuctionRefundsLosersAndForfeitsUnrevealedDeposits(t *testing.T) {
+	a := newAuctionTest(t, firstPriceAuction, 0)
+
+	winningBid := a.bid(bidder1, 50)
+	losingBid := a.bid(bidder2, 40)
+	a.bid(bidder3, 30)
+	a.reveal(bidder1, winningBid, 50)
+	a.reveal(bidder2, losingBid, 40)
+	a.end()
+
+	// the deposit of the winner is applied to the price
+	a.requireBalance(bidder1, 90)
+	a.requireBalance(bidder2, 100)
+	a.requireBalance(bidder3, 90)
+	a.requireBalance(seller, 10)
+
+	payment := a.requirePayment("pending")
+	require.Equal(t, 50, payment.Amount)
+	require.Equal(t, 10, payment.Deposit)
+
+	a.as(bidder2, 22, nil)
+	err := a.contract.PayAuction(a.ctx, depositAuctionID)
+	require.EqualError(t, err, "auction can only be paid by the winner")
+
+	a.as(bidder1, 22, nil)
+	require.NoError(t, a.contract.PayAuction(a.ctx, depositAuctionID))
+	a.requireBalance(bidder1, 50)
+	a.requireBalance(seller, 60)
+	a.requirePayment("paid")
+}
+
+func TestEndAuctionPaysPriceCoveredByDeposit(t *testing.T) {
+	a := newAuctionTest(t, secondPriceAuction, 5)
+
+	winningBid := a.bid(bidder1, 50)
+	a.reveal(bidder1, winningBid, 50)
+	a.end()
+
+	// the winner pays the minimum bid out of the deposit, and gets the rest back
+	a.requireBalance(bidder1, 95)
+	a.requireBalance(seller, 5)
+	a.requirePayment("paid")
+}
+
+func TestForfeitDepositAfterPaymentDeadline(t *testing.T) {
+	a := newAuctionTest(t, firstPriceAuction, 0)
+
+	winningBid := a.bid(bidder1, 50)
+	a.reveal(bidder1, winningBid, 50)
+	a.end()
+
+	a.as(seller, 29, nil)
+	err := a.contract.ForfeitDeposit(a.ctx, depositAuctionID)
+	require.EqualError(t, err, "cannot forfeit deposit before the payment deadline 2021-01-01T12:30:00Z")
+
+	a.as(seller, 30, nil)
+	require.NoError(t, a.contract.ForfeitDeposit(a.ctx, depositAuctionID))
+	a.requireBalance(bidder1, 90)
+	a.requireBalance(seller, 10)
+	a.requirePayment("forfeited")
+
+	a.as(bidder1, 31, nil)
+	err = a.contract.PayAuction(a.ctx, depositAuctionID)
+	require.EqualError(t, err, "payment of auction auction1 is not pending")
+}
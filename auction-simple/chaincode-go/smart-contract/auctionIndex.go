@@ -0,0 +1,79 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
+)
+
+// The auctions are indexed using composite keys, so that they can be listed without
+// reading every key of the world state. Each index entry ends with the auction ID
+const (
+	statusIndex  = "status~auction"
+	sellerIndex  = "seller~auction"
+	itemIndex    = "item~auction"
+	createdIndex = "created~auction"
+)
+
+// createdAtFormat is used to store the creation time of the auction in the created~auction
+// index. The timestamps have a fixed length so that the index is sorted by creation time
+const createdAtFormat = "2006-01-02T15:04:05.000000000Z"
+
+// putAuctionIndexes is an internal function that adds the index entries of a new auction.
+// Only the key name is needed, so the null character is used as value
+func putAuctionIndexes(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction) error {
+
+	indexes := map[string][]string{
+		statusIndex:  {auction.Status, auctionID},
+		sellerIndex:  {identity.Normalize(auction.Seller), auctionID},
+		itemIndex:    {auction.ItemSold, auctionID},
+		createdIndex: {auction.CreatedAt.UTC().Format(createdAtFormat), auctionID},
+	}
+
+	for _, indexName := range []string{statusIndex, sellerIndex, itemIndex, createdIndex} {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(indexName, indexes[indexName])
+		if err != nil {
+			return fmt.Errorf("failed to create composite key for index %s: %v", indexName, err)
+		}
+		err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to put index entry: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// updateStatusIndex is an internal function that moves an auction to the entry of its
+// new status in the status~auction index, when the status of the auction has changed
+func updateStatusIndex(ctx contractapi.TransactionContextInterface, auctionID string, previousStatus string, status string) error {
+
+	if previousStatus == status {
+		return nil
+	}
+
+	previousKey, err := ctx.GetStub().CreateCompositeKey(statusIndex, []string{previousStatus, auctionID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key for index %s: %v", statusIndex, err)
+	}
+	err = ctx.GetStub().DelState(previousKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete index entry: %v", err)
+	}
+
+	statusKey, err := ctx.GetStub().CreateCompositeKey(statusIndex, []string{status, auctionID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key for index %s: %v", statusIndex, err)
+	}
+	err = ctx.GetStub().PutState(statusKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put index entry: %v", err)
+	}
+
+	return nil
+}
@@ -7,9 +7,12 @@ package auction
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
 )
 
 // QueryAuction allows all members of the channel to read a public auction
@@ -70,13 +73,202 @@ func (s *SmartContract) QueryBid(ctx contractapi.TransactionContextInterface, au
 	}
 
 	// check that the client querying the bid is the bid owner
-	if bid.Bidder != clientID {
+	if !identity.Same(bid.Bidder, clientID) {
 		return nil, fmt.Errorf("Permission denied, client id %v is not the owner of the bid", clientID)
 	}
 
 	return bid, nil
 }
 
+// AuctionRecord structure used for returning an auction along with its ID
+type AuctionRecord struct {
+	AuctionID string   `json:"auctionID"`
+	Auction   *Auction `json:"auction"`
+}
+
+// PaginatedAuctionResult structure used for returning a page of auctions
+type PaginatedAuctionResult struct {
+	Records             []*AuctionRecord `json:"records"`
+	FetchedRecordsCount int32            `json:"fetchedRecordsCount"`
+	Bookmark            string           `json:"bookmark"`
+}
+
+// BidRecord structure used for returning a bid along with the auction it was created for
+type BidRecord struct {
+	AuctionID string   `json:"auctionID"`
+	BidID     string   `json:"bidID"`
+	Bid       *FullBid `json:"bid"`
+}
+
+// ListAuctions returns a page of the auctions that match all of the filters that are not empty.
+// Auctions can be filtered by status, seller, item, and by the auctions created after an RFC3339
+// timestamp. The auctions are read from the index of the first of the status, seller and item
+// filters that is provided, or from the created~auction index in the order they were created,
+// starting after the createdAfter timestamp. Pass the bookmark returned with a page to get the
+// next page, or an empty bookmark to get the first page. The bookmark is empty when all auctions
+// have been listed. The status of an auction is updated when the auction is closed or ended, so
+// an auction whose bidding deadline has passed is listed as open until a bid is revealed or the
+// auction is closed. Paginated queries cannot be used in transactions that are submitted.
+func (s *SmartContract) ListAuctions(ctx contractapi.TransactionContextInterface, status string, seller string, item string, createdAfter string, pageSize int, bookmark string) (*PaginatedAuctionResult, error) {
+
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("pageSize must be a positive integer")
+	}
+
+	var createdAfterTime time.Time
+	if createdAfter != "" {
+		var err error
+		createdAfterTime, err = time.Parse(time.RFC3339, createdAfter)
+		if err != nil {
+			return nil, fmt.Errorf("createdAfter must be an RFC3339 timestamp: %v", err)
+		}
+	}
+
+	indexName, attributes := createdIndex, []string{}
+	if status != "" {
+		indexName, attributes = statusIndex, []string{status}
+	} else if seller != "" {
+		indexName, attributes = sellerIndex, []string{identity.Normalize(seller)}
+	} else if item != "" {
+		indexName, attributes = itemIndex, []string{item}
+	}
+
+	// the bookmark is the key of the index entry that the page starts from. The first page of
+	// the created~auction index starts from the first auction created after the timestamp
+	if indexName == createdIndex && createdAfter != "" && bookmark == "" {
+		startAfter := createdAfterTime.Add(time.Nanosecond).UTC().Format(createdAtFormat)
+		var err error
+		bookmark, err = ctx.GetStub().CreateCompositeKey(createdIndex, []string{startAfter})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key for index %s: %v", createdIndex, err)
+		}
+	}
+
+	// auctions that do not match the other filters are skipped, so more index entries are read
+	// until the page is full or there are no more entries
+	result := &PaginatedAuctionResult{Records: []*AuctionRecord{}}
+	for {
+		remaining := int32(pageSize - len(result.Records))
+		resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(indexName, attributes, remaining, bookmark)
+		if err != nil {
+			return nil, err
+		}
+
+		records, err := s.readIndexedAuctions(ctx, resultsIterator, status, seller, item, createdAfter, createdAfterTime)
+		resultsIterator.Close()
+		if err != nil {
+			return nil, err
+		}
+		result.Records = append(result.Records, records...)
+
+		bookmark = metadata.Bookmark
+		if metadata.FetchedRecordsCount < remaining {
+			bookmark = ""
+		}
+		if bookmark == "" || len(result.Records) == pageSize {
+			break
+		}
+	}
+	result.Bookmark = bookmark
+	result.FetchedRecordsCount = int32(len(result.Records))
+
+	return result, nil
+}
+
+// readIndexedAuctions is an internal function that reads the auctions of the index entries
+// returned by the iterator, and returns the auctions that match all of the filters
+func (s *SmartContract) readIndexedAuctions(ctx contractapi.TransactionContextInterface, resultsIterator shim.StateQueryIteratorInterface, status string, seller string, item string, createdAfter string, createdAfterTime time.Time) ([]*AuctionRecord, error) {
+
+	var records []*AuctionRecord
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResult.Key)
+		if err != nil {
+			return nil, err
+		}
+		auctionID := compositeKeyParts[len(compositeKeyParts)-1]
+
+		auction, err := s.QueryAuction(ctx, auctionID)
+		if err != nil {
+			return nil, err
+		}
+
+		if (status != "" && auction.Status != status) ||
+			(seller != "" && !identity.Same(auction.Seller, seller)) ||
+			(item != "" && auction.ItemSold != item) ||
+			(createdAfter != "" && !auction.CreatedAt.After(createdAfterTime)) {
+			continue
+		}
+
+		records = append(records, &AuctionRecord{AuctionID: auctionID, Auction: auction})
+	}
+
+	return records, nil
+}
+
+// ListMyBids returns the bids of the submitting client across all auctions. The bids are
+// read from the implicit collection of the client's organization, so the client needs
+// to target a peer of their organization. Bids that were withdrawn are not returned
+func (s *SmartContract) ListMyBids(ctx contractapi.TransactionContextInterface) ([]*BidRecord, error) {
+
+	err := verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(collection, bidKeyType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bids from collection: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	bids := []*BidRecord{}
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResult.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		var bid *FullBid
+		err = json.Unmarshal(queryResult.Value, &bid)
+		if err != nil {
+			return nil, err
+		}
+
+		// the collection holds the bids of all the clients of the organization
+		if !identity.Same(bid.Bidder, clientID) {
+			continue
+		}
+
+		bids = append(bids, &BidRecord{
+			AuctionID: compositeKeyParts[0],
+			BidID:     compositeKeyParts[1],
+			Bid:       bid,
+		})
+	}
+
+	return bids, nil
+}
+
 // checkForHigherBid is an internal function that is used to determine if a winning bid has yet to be revealed
 func checkForHigherBid(ctx contractapi.TransactionContextInterface, auctionPrice int, revealedBidders map[string]FullBid, bidders map[string]BidHash) error {
 
@@ -134,3 +326,26 @@ func checkForHigherBid(ctx contractapi.TransactionContextInterface, auctionPrice
 
 	return error
 }
+
+// rankRevealedBids returns the keys of the revealed bids from the highest to the lowest price.
+// Bids with the same price are ranked by bid key, so that the ranking does not depend on the
+// order of iteration over the map. The tie-break is deterministic but not fair: the bid key is
+// derived from the transaction ID of the bid, which a bidder can influence by choosing the
+// nonce of the transaction until the ID ranks first.
+func rankRevealedBids(revealedBids map[string]FullBid) []string {
+
+	var bidKeys []string
+	for bidKey := range revealedBids {
+		bidKeys = append(bidKeys, bidKey)
+	}
+
+	sort.Slice(bidKeys, func(p, q int) bool {
+		bidP, bidQ := revealedBids[bidKeys[p]], revealedBids[bidKeys[q]]
+		if bidP.Price != bidQ.Price {
+			return bidP.Price > bidQ.Price
+		}
+		return bidKeys[p] < bidKeys[q]
+	})
+
+	return bidKeys
+}
@@ -0,0 +1,165 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
+)
+
+// QueryReservePrice allows the seller to read the reserve price of their auction from
+// the implicit collection of their organization
+func (s *SmartContract) QueryReservePrice(ctx contractapi.TransactionContextInterface, auctionID string) (*ReservePrice, error) {
+
+	err := verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction from public state %v", err)
+	}
+
+	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity %v", err)
+	}
+	if !identity.Same(auction.Seller, clientID) {
+		return nil, fmt.Errorf("reserve price can only be read by the seller")
+	}
+
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	reserveKey, err := ctx.GetStub().CreateCompositeKey(reserveKeyType, []string{auctionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	reserveJSON, err := ctx.GetStub().GetPrivateData(collection, reserveKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reserve price %v: %v", reserveKey, err)
+	}
+	if reserveJSON == nil {
+		return nil, fmt.Errorf("auction %v does not have a reserve price", auctionID)
+	}
+
+	var reserve *ReservePrice
+	err = json.Unmarshal(reserveJSON, &reserve)
+	if err != nil {
+		return nil, err
+	}
+
+	return reserve, nil
+}
+
+// putReservePrice is an internal function that stores the reserve price passed in the
+// transient map in the implicit collection of the seller. It returns the hash of the
+// reserve price, or an empty string if the auction does not have a reserve price
+func putReservePrice(ctx contractapi.TransactionContextInterface, auctionID string) (string, error) {
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("error getting transient: %v", err)
+	}
+
+	transientReserveJSON, ok := transientMap["reserve"]
+	if !ok {
+		return "", nil
+	}
+
+	reserveJSON, err := marshalReservePrice(transientReserveJSON)
+	if err != nil {
+		return "", err
+	}
+
+	// the seller has to target their peer to store the reserve price
+	err = verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Cannot store reserve price on this peer, not a member of this org: Error %v", err)
+	}
+
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	reserveKey, err := ctx.GetStub().CreateCompositeKey(reserveKeyType, []string{auctionID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	err = ctx.GetStub().PutPrivateData(collection, reserveKey, reserveJSON)
+	if err != nil {
+		return "", fmt.Errorf("failed to input reserve price into collection: %v", err)
+	}
+
+	// the hash is the same as the hash of the private data on the public ledger
+	return fmt.Sprintf("%x", sha256.Sum256(reserveJSON)), nil
+}
+
+// readRevealedReservePrice is an internal function that reads the reserve price revealed
+// by the seller in the transient map, and checks that it matches the hash on the auction.
+// It returns nil if the reserve price is not in the transient map
+func readRevealedReservePrice(ctx contractapi.TransactionContextInterface, reserveHash string) (*ReservePrice, error) {
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("error getting transient: %v", err)
+	}
+
+	transientReserveJSON, ok := transientMap["reserve"]
+	if !ok {
+		return nil, nil
+	}
+
+	reserveJSON, err := marshalReservePrice(transientReserveJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	calculatedHash := fmt.Sprintf("%x", sha256.Sum256(reserveJSON))
+	if calculatedHash != reserveHash {
+		return nil, fmt.Errorf("hash %s for reserve price JSON %s does not match hash in auction: %s",
+			calculatedHash,
+			reserveJSON,
+			reserveHash,
+		)
+	}
+
+	var reserve *ReservePrice
+	err = json.Unmarshal(reserveJSON, &reserve)
+	if err != nil {
+		return nil, err
+	}
+
+	return reserve, nil
+}
+
+// marshalReservePrice validates the reserve price and marshals it in a canonical form,
+// so that the hash does not depend on the formatting of the JSON passed by the seller
+func marshalReservePrice(transientReserveJSON []byte) ([]byte, error) {
+
+	var reserve ReservePrice
+	err := json.Unmarshal(transientReserveJSON, &reserve)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+	if reserve.Price <= 0 {
+		return nil, fmt.Errorf("reserve price must be a positive integer")
+	}
+	if len(reserve.Salt) < minSaltLength {
+		return nil, fmt.Errorf("reserve price requires a random salt of at least %d characters", minSaltLength)
+	}
+
+	return json.Marshal(reserve)
+}
@@ -0,0 +1,34 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankRevealedBidsBreaksTiesByBidKey(t *testing.T) {
+	revealedBids := map[string]FullBid{
+		"bid-c": {Price: 80},
+		"bid-b": {Price: 100},
+		"bid-a": {Price: 100},
+		"bid-d": {Price: 120},
+	}
+
+	for i := 0; i < 10; i++ {
+		require.Equal(t, []string{"bid-d", "bid-a", "bid-b", "bid-c"}, rankRevealedBids(revealedBids))
+	}
+}
+
+func TestCreateAuctionRequiresMinimumBidForSecondPrice(t *testing.T) {
+	s := SmartContract{}
+
+	err := s.CreateAuction(nil, "auction1", "painting", "", secondPriceAuction, 0, 0, "", "", "")
+	require.EqualError(t, err, "second price auctions require a positive minimum bid")
+
+	err = s.CreateAuction(nil, "auction1", "painting", "", "thirdPrice", 10, 0, "", "", "")
+	require.EqualError(t, err, "auction type must be firstPrice or secondPrice")
+}
@@ -6,24 +6,18 @@ package auction
 
 import (
 	"fmt"
-	"encoding/base64"
+	"time"
 
 	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
 )
 
+// GetSubmittingClientIdentity returns the MSP ID, name and issuer of the identity
+// that invokes the smart contract, in the <mspID>::x509::<subject>::<issuer> format.
 func (s *SmartContract) GetSubmittingClientIdentity(ctx contractapi.TransactionContextInterface) (string, error) {
-
-	b64ID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return "", fmt.Errorf("Failed to read clientID: %v", err)
-	}
-	decodeID, err := base64.StdEncoding.DecodeString(b64ID)
-	if err != nil {
-		return "", fmt.Errorf("failed to base64 decode clientID: %v", err)
-	}
-	return string(decodeID), nil
+	return identity.ID(ctx.GetClientIdentity())
 }
 
 // setAssetStateBasedEndorsement sets the endorsement policy of a new auction
@@ -119,3 +113,67 @@ func contains(sli []string, str string) bool {
 	}
 	return false
 }
+
+// getTxTimestamp returns the timestamp of the transaction, which is set by the client
+// and is the same on all endorsing peers
+func getTxTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	return time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC(), nil
+}
+
+// parseDeadlines is an internal function that parses the bidding and reveal deadlines of
+// a new auction, and checks that the bidding deadline is in the future and before the reveal deadline
+func parseDeadlines(ctx contractapi.TransactionContextInterface, biddingDeadline string, revealDeadline string) (time.Time, time.Time, error) {
+
+	biddingDeadlineTime, err := time.Parse(time.RFC3339, biddingDeadline)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("bidding deadline must be an RFC3339 timestamp: %v", err)
+	}
+	revealDeadlineTime, err := time.Parse(time.RFC3339, revealDeadline)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("reveal deadline must be an RFC3339 timestamp: %v", err)
+	}
+
+	txTime, err := getTxTimestamp(ctx)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !biddingDeadlineTime.After(txTime) {
+		return time.Time{}, time.Time{}, fmt.Errorf("bidding deadline %s is not after the transaction timestamp %s", biddingDeadline, txTime.Format(time.RFC3339))
+	}
+	if !revealDeadlineTime.After(biddingDeadlineTime) {
+		return time.Time{}, time.Time{}, fmt.Errorf("reveal deadline %s is not after the bidding deadline %s", revealDeadline, biddingDeadline)
+	}
+
+	return biddingDeadlineTime.UTC(), revealDeadlineTime.UTC(), nil
+}
+
+// parsePaymentDeadline is an internal function that parses the payment deadline of a new
+// auction with a deposit, and checks that it is after the reveal deadline
+func parsePaymentDeadline(paymentDeadline string, revealDeadline time.Time) (time.Time, error) {
+
+	paymentDeadlineTime, err := time.Parse(time.RFC3339, paymentDeadline)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("payment deadline must be an RFC3339 timestamp: %v", err)
+	}
+	if !paymentDeadlineTime.After(revealDeadline) {
+		return time.Time{}, fmt.Errorf("payment deadline %s is not after the reveal deadline %s", paymentDeadline, revealDeadline.Format(time.RFC3339))
+	}
+
+	return paymentDeadlineTime.UTC(), nil
+}
+
+// closeAfterBiddingDeadline is an internal function that closes an open auction once the
+// bidding deadline has passed, so that bids can be revealed without waiting for the auction
+// to be closed
+func closeAfterBiddingDeadline(auction *Auction, txTime time.Time) {
+
+	if auction.Status == "open" && !txTime.Before(auction.BiddingDeadline) {
+		auction.Status = string("closed")
+	}
+}
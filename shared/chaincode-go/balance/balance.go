@@ -0,0 +1,127 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package balance keeps a ledger of fungible units held by client identities in public state.
+// The auction samples use the units to escrow bid deposits and to pay auction prices. Each
+// balance is endorsed by the organization of the client that holds it, so that units can
+// only be moved with the endorsement of the organizations of the accounts involved.
+package balance
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/identity"
+)
+
+// KeyType is the object type of the composite keys of the balances
+const KeyType = "balance"
+
+// Balance is the number of units held by an account. Accounts are identified by the
+// identity string of the client, which includes the MSP ID of the client
+type Balance struct {
+	Type    string `json:"objectType"`
+	Account string `json:"account"`
+	Amount  int    `json:"amount"`
+}
+
+// Read returns the balance of an account. Accounts that never held any units have a balance of 0
+func Read(stub shim.ChaincodeStubInterface, account string) (int, error) {
+	balanceKey, err := stub.CreateCompositeKey(KeyType, []string{identity.Normalize(account)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	balanceJSON, err := stub.GetState(balanceKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get balance of %v: %v", account, err)
+	}
+	if balanceJSON == nil {
+		return 0, nil
+	}
+
+	var balance Balance
+	err = json.Unmarshal(balanceJSON, &balance)
+	if err != nil {
+		return 0, err
+	}
+
+	return balance.Amount, nil
+}
+
+// Apply adds the changes to the balances of the accounts. The changes of an account are added
+// up before the balance is updated, because a transaction does not read its own writes. No
+// balance can become negative. The organization of each account is set as the endorser of its
+// balance, so that the balance cannot be changed without the endorsement of that organization
+func Apply(stub shim.ChaincodeStubInterface, changes map[string]int) error {
+	accountChanges := make(map[string]int)
+	for account, change := range changes {
+		accountChanges[identity.Normalize(account)] += change
+	}
+
+	for account, change := range accountChanges {
+		if change == 0 {
+			continue
+		}
+
+		mspID := identity.MSPID(account)
+		if mspID == "" {
+			return fmt.Errorf("account %v is not a client identity with an MSP ID", account)
+		}
+
+		amount, err := Read(stub, account)
+		if err != nil {
+			return err
+		}
+		if amount+change < 0 {
+			return fmt.Errorf("insufficient balance, account %v holds %d units and needs %d", account, amount, -change)
+		}
+
+		balanceJSON, err := json.Marshal(Balance{Type: KeyType, Account: account, Amount: amount + change})
+		if err != nil {
+			return err
+		}
+
+		balanceKey, err := stub.CreateCompositeKey(KeyType, []string{account})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+
+		err = stub.PutState(balanceKey, balanceJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put balance of %v: %v", account, err)
+		}
+
+		err = setEndorsingOrg(stub, balanceKey, mspID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setEndorsingOrg sets the organization as the only endorser of the key
+func setEndorsingOrg(stub shim.ChaincodeStubInterface, key string, mspID string) error {
+	endorsementPolicy, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return err
+	}
+	err = endorsementPolicy.AddOrgs(statebased.RoleTypePeer, mspID)
+	if err != nil {
+		return fmt.Errorf("failed to add org to endorsement policy: %v", err)
+	}
+	policy, err := endorsementPolicy.Policy()
+	if err != nil {
+		return fmt.Errorf("failed to create endorsement policy bytes from org: %v", err)
+	}
+	err = stub.SetStateValidationParameter(key, policy)
+	if err != nil {
+		return fmt.Errorf("failed to set validation parameter on balance: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package balance
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-samples/shared/chaincode-go/chaincodetest"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	alice = chaincodetest.NewClientIdentity("Org1MSP", "alice").ID()
+	bob   = chaincodetest.NewClientIdentity("Org2MSP", "bob").ID()
+)
+
+func TestApplyConservesUnits(t *testing.T) {
+	stub := chaincodetest.NewStub("balance")
+	require.NoError(t, Apply(stub, map[string]int{alice: 100}))
+
+	require.NoError(t, Apply(stub, map[string]int{alice: -30, bob: 30}))
+	requireBalance(t, stub, alice, 70)
+	requireBalance(t, stub, bob, 30)
+}
+
+func TestApplyAddsUpChangesOfAnAccount(t *testing.T) {
+	stub := chaincodetest.NewStub("balance")
+	require.NoError(t, Apply(stub, map[string]int{alice: 100}))
+
+	// the same account in another spelling is the same balance
+	aliceSpaced := "Org1MSP::x509::CN=alice, OU=client::CN=ca.Org1MSP"
+	require.NoError(t, Apply(stub, map[string]int{alice: -100, aliceSpaced: 60}))
+	requireBalance(t, stub, alice, 60)
+}
+
+func TestApplyRejectsNegativeBalance(t *testing.T) {
+	stub := chaincodetest.NewStub("balance")
+	require.NoError(t, Apply(stub, map[string]int{alice: 10}))
+
+	err := Apply(stub, map[string]int{alice: -11, bob: 11})
+	require.Error(t, err)
+	requireBalance(t, stub, alice, 10)
+}
+
+func TestApplyRejectsAccountsWithoutMSPID(t *testing.T) {
+	stub := chaincodetest.NewStub("balance")
+
+	err := Apply(stub, map[string]int{"x509::CN=alice,OU=client::CN=ca.Org1MSP": 10})
+	require.EqualError(t, err, "account x509::CN=alice,OU=client::CN=ca.Org1MSP is not a client identity with an MSP ID")
+}
+
+func TestApplySetsTheOrgOfTheAccountAsEndorser(t *testing.T) {
+	stub := chaincodetest.NewStub("balance")
+	require.NoError(t, Apply(stub, map[string]int{bob: 10}))
+
+	balanceKey, err := stub.CreateCompositeKey(KeyType, []string{bob})
+	require.NoError(t, err)
+	policy, err := stub.GetStateValidationParameter(balanceKey)
+	require.NoError(t, err)
+
+	endorsementPolicy, err := statebased.NewStateEP(policy)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Org2MSP"}, endorsementPolicy.ListOrgs())
+}
+
+func requireBalance(t *testing.T, stub *chaincodetest.Stub, account string, amount int) {
+	balance, err := Read(stub, account)
+	require.NoError(t, err)
+	require.Equal(t, amount, balance)
+}
@@ -0,0 +1,124 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package chaincodetest provides an in-memory ledger and client identities for the unit
+// tests of the chaincode samples. It extends the mock stub of the shim with the transient
+// map, the transaction timestamp and the private data functions that the samples use.
+package chaincodetest
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+)
+
+// Stub is an in-memory ledger that runs one transaction at a time. Unlike a peer, it reads
+// the writes of the current transaction, so each step of a test is run in a new transaction
+type Stub struct {
+	*shimtest.MockStub
+	Transient map[string][]byte
+	txCount   int
+}
+
+// NewStub returns an empty ledger with a transaction started at the current time
+func NewStub(name string) *Stub {
+	stub := &Stub{MockStub: shimtest.NewMockStub(name, nil)}
+	stub.StartTransaction(time.Now(), nil)
+	return stub
+}
+
+// StartTransaction starts a new transaction with a new transaction ID, the timestamp
+// and the transient map that are set by the client that submits the transaction
+func (s *Stub) StartTransaction(txTime time.Time, transient map[string][]byte) {
+	s.txCount++
+	s.MockTransactionStart(fmt.Sprintf("tx%d", s.txCount))
+	s.TxTimestamp = &timestamp.Timestamp{Seconds: txTime.Unix(), Nanos: int32(txTime.Nanosecond())}
+	s.Transient = transient
+}
+
+// GetTransient returns the transient map of the current transaction
+func (s *Stub) GetTransient() (map[string][]byte, error) {
+	return s.Transient, nil
+}
+
+// GetPrivateDataHash returns the SHA256 hash of a private data value, or nil if it does not exist
+func (s *Stub) GetPrivateDataHash(collection string, key string) ([]byte, error) {
+	value, err := s.GetPrivateData(collection, key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+// DelPrivateData deletes a private data value
+func (s *Stub) DelPrivateData(collection string, key string) error {
+	delete(s.PvtState[collection], key)
+	return nil
+}
+
+// ClientIdentity is a client that submits transactions, identified by its MSP ID and the
+// subject and issuer of its certificate
+type ClientIdentity struct {
+	MSPID      string
+	Subject    string
+	Issuer     string
+	Attributes map[string]string
+}
+
+// NewClientIdentity returns a client of the organization with the common name
+func NewClientIdentity(mspID string, name string) *ClientIdentity {
+	return &ClientIdentity{
+		MSPID:   mspID,
+		Subject: "CN=" + name + ",OU=client",
+		Issuer:  "CN=ca." + mspID,
+	}
+}
+
+// ID returns the identity string of the client, in the same format as identity.ID
+func (c *ClientIdentity) ID() string {
+	return c.MSPID + "::x509::" + c.Subject + "::" + c.Issuer
+}
+
+// UsePeerOf sets the organization of the peer that endorses the next transactions to the
+// organization of the client
+func (c *ClientIdentity) UsePeerOf() {
+	os.Setenv("CORE_PEER_LOCALMSPID", c.MSPID)
+}
+
+// GetID returns the base64 encoded ID of the client, as returned by the client identity of the shim
+func (c *ClientIdentity) GetID() (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte("x509::" + c.Subject + "::" + c.Issuer)), nil
+}
+
+// GetMSPID returns the MSP ID of the client
+func (c *ClientIdentity) GetMSPID() (string, error) {
+	return c.MSPID, nil
+}
+
+// GetAttributeValue returns the value of a certificate attribute of the client
+func (c *ClientIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	value, ok := c.Attributes[attrName]
+	return value, ok, nil
+}
+
+// AssertAttributeValue checks that the client has a certificate attribute with the value
+func (c *ClientIdentity) AssertAttributeValue(attrName string, attrValue string) error {
+	if value, ok := c.Attributes[attrName]; !ok || value != attrValue {
+		return fmt.Errorf("attribute '%s' does not equal '%s'", attrName, attrValue)
+	}
+	return nil
+}
+
+// GetX509Certificate returns nil, as the client does not have a certificate
+func (c *ClientIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return nil, nil
+}
@@ -0,0 +1,9 @@
+module github.com/hyperledger/fabric-samples/shared/chaincode-go
+
+go 1.14
+
+require (
+	github.com/golang/protobuf v1.3.2
+	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200424173110-d7076418f212
+	github.com/stretchr/testify v1.5.1
+)
@@ -0,0 +1,282 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package identity describes and compares the client identities that submit transactions.
+// It is shared by the chaincode samples, which require it with a replace directive. The
+// network.sh deployCC command vendors it into the chaincode package.
+package identity
+
+import (
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/attrmgr"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+)
+
+// DistinguishedName holds the fields of the subject or issuer of a certificate
+type DistinguishedName struct {
+	CommonName         string   `json:"commonName"`
+	Organization       []string `json:"organization,omitempty"`
+	OrganizationalUnit []string `json:"organizationalUnit,omitempty"`
+	Locality           []string `json:"locality,omitempty"`
+	Province           []string `json:"province,omitempty"`
+	Country            []string `json:"country,omitempty"`
+	String             string   `json:"dn"`
+}
+
+// ClientIdentityInfo describes the identity that submits a transaction
+type ClientIdentityInfo struct {
+	ID           string            `json:"ID"`
+	MSPID        string            `json:"mspID"`
+	Subject      DistinguishedName `json:"subject"`
+	Issuer       DistinguishedName `json:"issuer"`
+	SerialNumber string            `json:"serialNumber"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	NotBefore    time.Time         `json:"notBefore"`
+	NotAfter     time.Time         `json:"notAfter"`
+}
+
+// ID returns the identity string of the client in the <mspID>::x509::<subject>::<issuer> format.
+// The MSP ID is part of the identity, so that a certificate with the same subject and issuer
+// that is issued by the CA of another organization is a different identity.
+func ID(clientIdentity cid.ClientIdentity) (string, error) {
+	b64ID, err := clientIdentity.GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to read clientID: %v", err)
+	}
+	decodeID, err := base64.StdEncoding.DecodeString(b64ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to base64 decode clientID: %v", err)
+	}
+
+	mspID, err := clientIdentity.GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	return mspID + "::" + string(decodeID), nil
+}
+
+// Describe returns the client identity, as read from its certificate
+func Describe(clientIdentity cid.ClientIdentity) (*ClientIdentityInfo, error) {
+	id, err := ID(clientIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	mspID, err := clientIdentity.GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	cert, err := clientIdentity.GetX509Certificate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client certificate: %v", err)
+	}
+	if cert == nil {
+		return nil, fmt.Errorf("client identity is not an X.509 certificate")
+	}
+
+	attrs, err := attrmgr.New().GetAttributesFromCert(cert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attributes from client certificate: %v", err)
+	}
+
+	return &ClientIdentityInfo{
+		ID:           id,
+		MSPID:        mspID,
+		Subject:      newDistinguishedName(cert.Subject),
+		Issuer:       newDistinguishedName(cert.Issuer),
+		SerialNumber: cert.SerialNumber.Text(16),
+		Attributes:   attrs.Attrs,
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+	}, nil
+}
+
+func newDistinguishedName(name pkix.Name) DistinguishedName {
+	return DistinguishedName{
+		CommonName:         name.CommonName,
+		Organization:       name.Organization,
+		OrganizationalUnit: name.OrganizationalUnit,
+		Locality:           name.Locality,
+		Province:           name.Province,
+		Country:            name.Country,
+		String:             name.String(),
+	}
+}
+
+// Same compares two identity strings, as returned by ID. The MSP IDs must be equal, and the
+// subject and issuer are compared by their attributes, so that identities match regardless of
+// the spacing or escaping of the attributes, and of the order of the attributes within a
+// multi-valued relative distinguished name.
+func Same(a string, b string) bool {
+	return Normalize(a) == Normalize(b)
+}
+
+// Normalize returns the identity string with the subject and issuer in a canonical form.
+// Strings that are not in the <mspID>::x509::<subject>::<issuer> format are returned unchanged.
+func Normalize(id string) string {
+	parts := strings.Split(id, "::")
+	if len(parts) != 4 || parts[1] != "x509" {
+		return id
+	}
+	return parts[0] + "::x509::" + normalizeDN(parts[2]) + "::" + normalizeDN(parts[3])
+}
+
+// MSPID returns the MSP ID of an identity string in the <mspID>::x509::<subject>::<issuer> format,
+// or an empty string if the identity string is in another format
+func MSPID(id string) string {
+	parts := strings.Split(id, "::")
+	if len(parts) != 4 || parts[1] != "x509" {
+		return ""
+	}
+	return parts[0]
+}
+
+// AssertAdmin checks that the client is an admin of its organization, either by the admin
+// organizational unit of its certificate or by the hf.Type attribute added by the Fabric CA
+func AssertAdmin(clientIdentity cid.ClientIdentity) error {
+	err := clientIdentity.AssertAttributeValue("hf.Type", "admin")
+	if err == nil {
+		return nil
+	}
+
+	cert, err := clientIdentity.GetX509Certificate()
+	if err != nil {
+		return fmt.Errorf("failed to get client certificate: %v", err)
+	}
+	if cert == nil {
+		return fmt.Errorf("submitting client is not an admin")
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if strings.EqualFold(ou, "admin") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("submitting client is not an admin")
+}
+
+// normalizeDN sorts the attributes within each relative distinguished name. The order of the
+// relative distinguished names is significant and is kept. The attributes of a multi-valued
+// relative distinguished name stay joined by a plus sign, so that they cannot be confused with
+// single-valued ones.
+func normalizeDN(dn string) string {
+	rdns := parseDN(dn)
+	normalized := make([]string, len(rdns))
+	for i, attributes := range rdns {
+		sort.Strings(attributes)
+		normalized[i] = strings.Join(attributes, "+")
+	}
+
+	return strings.Join(normalized, ",")
+}
+
+// parseDN splits a distinguished name in the string format of RFC 4514 into its relative
+// distinguished names, each holding one or more attributes in the canonical TYPE=value form.
+// Separators that are escaped with a backslash or quoted are part of the attribute value.
+func parseDN(dn string) [][]string {
+	var rdns [][]string
+	var attributes []string
+	var current strings.Builder
+	escaped := false
+	quoted := false
+	for _, r := range dn {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (r == ',' || r == '+'):
+			attributes = append(attributes, normalizeAttribute(current.String()))
+			current.Reset()
+			if r == ',' {
+				rdns = append(rdns, attributes)
+				attributes = nil
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	attributes = append(attributes, normalizeAttribute(current.String()))
+
+	return append(rdns, attributes)
+}
+
+// normalizeAttribute returns the attribute with its type in upper case and its value escaped
+// in the same way as the Go x509 package escapes the values of an identity string
+func normalizeAttribute(attribute string) string {
+	typeValue := strings.SplitN(attribute, "=", 2)
+	if len(typeValue) != 2 {
+		return strings.TrimSpace(attribute)
+	}
+	return strings.ToUpper(strings.TrimSpace(typeValue[0])) + "=" + escapeValue(unescapeValue(typeValue[1]))
+}
+
+// unescapeValue removes the quotes, escapes and surrounding spaces from an attribute value
+func unescapeValue(value string) string {
+	value = strings.TrimLeft(value, " ")
+
+	var unescaped []byte
+	trailingSpaces := 0
+	quoted := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\' && i+2 < len(value) && isHex(value[i+1]) && isHex(value[i+2]):
+			b, _ := hex.DecodeString(value[i+1 : i+3])
+			unescaped = append(unescaped, b...)
+			trailingSpaces = 0
+			i += 2
+		case c == '\\' && i+1 < len(value):
+			unescaped = append(unescaped, value[i+1])
+			trailingSpaces = 0
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == ' ' && !quoted:
+			unescaped = append(unescaped, c)
+			trailingSpaces++
+		default:
+			unescaped = append(unescaped, c)
+			trailingSpaces = 0
+		}
+	}
+
+	return string(unescaped[:len(unescaped)-trailingSpaces])
+}
+
+// escapeValue escapes the special characters of an attribute value as described in RFC 4514
+func escapeValue(value string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case strings.IndexByte(",+\"\\<>;", c) >= 0,
+			i == 0 && (c == ' ' || c == '#'),
+			i == len(value)-1 && c == ' ':
+			escaped.WriteByte('\\')
+			escaped.WriteByte(c)
+		case c == 0:
+			escaped.WriteString("\\00")
+		default:
+			escaped.WriteByte(c)
+		}
+	}
+
+	return escaped.String()
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,66 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package identity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const issuer = "CN=ca.org1.example.com,O=org1.example.com,L=Durham,ST=North Carolina,C=US"
+
+func TestSameIgnoresAttributeOrderAndSpacing(t *testing.T) {
+	a := "Org1MSP::x509::CN=user1,OU=client+OU=org1,O=Hyperledger::" + issuer
+	b := "Org1MSP::x509::cn=user1 , OU=org1 + OU=client,O=Hyperledger::CN=ca.org1.example.com, O=org1.example.com, L=Durham, ST=North Carolina, C=US"
+	require.True(t, Same(a, b))
+}
+
+func TestSameKeepsRDNOrder(t *testing.T) {
+	a := "Org1MSP::x509::CN=user1,O=Hyperledger::" + issuer
+	b := "Org1MSP::x509::O=Hyperledger,CN=user1::" + issuer
+	require.False(t, Same(a, b))
+}
+
+func TestSameComparesMSPID(t *testing.T) {
+	a := "Org1MSP::x509::CN=user1,O=Hyperledger::" + issuer
+	b := "Org2MSP::x509::CN=user1,O=Hyperledger::" + issuer
+	require.False(t, Same(a, b))
+	require.False(t, Same(a, "x509::CN=user1,O=Hyperledger::"+issuer))
+}
+
+func TestSameKeepsMultiValuedRDNs(t *testing.T) {
+	multiValued := "Org1MSP::x509::CN=user1+OU=client,O=Hyperledger::" + issuer
+	singleValued := "Org1MSP::x509::CN=user1,OU=client,O=Hyperledger::" + issuer
+	require.False(t, Same(multiValued, singleValued))
+}
+
+func TestSameKeepsEscapedSeparators(t *testing.T) {
+	escaped := "Org1MSP::x509::CN=user1\\,OU=client::" + issuer
+	separated := "Org1MSP::x509::CN=user1,OU=client::" + issuer
+	require.False(t, Same(escaped, separated))
+
+	escapedPlus := "Org1MSP::x509::CN=user1\\+OU=client::" + issuer
+	multiValued := "Org1MSP::x509::CN=user1+OU=client::" + issuer
+	require.False(t, Same(escapedPlus, multiValued))
+}
+
+func TestSameDecodesEscapes(t *testing.T) {
+	backslash := "Org1MSP::x509::CN=user1\\,admin::" + issuer
+	hexPair := "Org1MSP::x509::CN=user1\\2Cadmin::" + issuer
+	quoted := "Org1MSP::x509::CN=\"user1,admin\"::" + issuer
+	require.True(t, Same(backslash, hexPair))
+	require.True(t, Same(backslash, quoted))
+}
+
+func TestSameKeepsEscapedSpaces(t *testing.T) {
+	require.False(t, Same("Org1MSP::x509::CN=user1\\ ::"+issuer, "Org1MSP::x509::CN=user1::"+issuer))
+	require.True(t, Same("Org1MSP::x509::CN=user1 ::"+issuer, "Org1MSP::x509::CN=user1::"+issuer))
+}
+
+func TestNormalizeReturnsOtherFormatsUnchanged(t *testing.T) {
+	require.Equal(t, "not an identity", Normalize("not an identity"))
+	require.False(t, Same("user1", "User1"))
+}